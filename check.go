@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-spatial/geom"
+	"github.com/iancoleman/strcase"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/processing/gpkg"
+	"github.com/pdok/texel/tms20"
+	"github.com/pdok/texel/validate"
+	"github.com/urfave/cli/v2"
+)
+
+type validateMode string
+
+const (
+	validateOff        validateMode = ""
+	validateFail       validateMode = "fail"
+	validateQuarantine validateMode = "quarantine"
+)
+
+func parseValidateMode(s string) (validateMode, error) {
+	switch m := validateMode(s); m {
+	case validateOff, validateFail, validateQuarantine:
+		return m, nil
+	default:
+		return validateOff, fmt.Errorf(`unknown validate mode "%s", should be "%s" or "%s"`, s, validateFail, validateQuarantine)
+	}
+}
+
+// validateSnapped validates the result of snapping a feature (all polygons of a multipolygon together).
+// Depending on the mode an invalid result fails or the invalid tile matrices are left out (quarantined),
+// in a new map (the argument is left as is). The findings are returned too.
+func validateSnapped(fid int64, newPolygonsPerTileMatrix map[tms20.TMID][]geom.Polygon, mode validateMode) (map[tms20.TMID][]geom.Polygon, []validate.Finding, error) {
+	if mode == validateOff {
+		return newPolygonsPerTileMatrix, nil, nil
+	}
+	findings := validate.TileMatrixPolygons(newPolygonsPerTileMatrix)
+	if len(findings) == 0 {
+		return newPolygonsPerTileMatrix, nil, nil
+	}
+	for i := range findings {
+		findings[i].FID = fid
+	}
+	if mode == validateFail {
+		return nil, findings, fmt.Errorf("invalid snapped feature: %v", findings[0])
+	}
+	quarantined := make(map[tms20.TMID]bool, len(findings))
+	for _, finding := range findings {
+		slog.Warn("quarantined", "fid", finding.FID, "tmID", finding.TileMatrixID, "kind", finding.Kind, "location", finding.Location)
+		quarantined[finding.TileMatrixID] = true
+	}
+	validPolygonsPerTileMatrix := make(map[tms20.TMID][]geom.Polygon, len(newPolygonsPerTileMatrix))
+	for tmID, newPolygons := range newPolygonsPerTileMatrix {
+		if !quarantined[tmID] {
+			validPolygonsPerTileMatrix[tmID] = newPolygons
+		}
+	}
+	return validPolygonsPerTileMatrix, findings, nil
+}
+
+func checkCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "check",
+		Usage: "Check the (MULTI)POLYGONS in snapped target GPKGs against texel's definition of valid",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     TARGET,
+				Aliases:  []string{"t"},
+				Usage:    "Target GPKG (prefix), as used for snapping. E.g. target.gpkg for target_6.gpkg",
+				Required: true,
+				EnvVars:  []string{strcase.ToScreamingSnake(TARGET)},
+			},
+			&cli.StringFlag{
+				Name:     TILEMATRICES,
+				Aliases:  []string{"z"},
+				Usage:    `IDs of the tile matrices to check the target GPKGs for. JSON array of integers. E.g.: [4,5,6,7,8]`,
+				Required: true,
+				EnvVars:  []string{strcase.ToScreamingSnake(TILEMATRICES)},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			var tileMatrixIDs []tms20.TMID
+			if err := json.Unmarshal([]byte(c.String(TILEMATRICES)), &tileMatrixIDs); err != nil {
+				return err
+			}
+			targetPathFmt := injectSuffixIntoPath(c.String(TARGET))
+			findingsCount := 0
+			for _, tmID := range tileMatrixIDs {
+				targetPath := fmt.Sprintf(targetPathFmt, tmID)
+				if _, err := os.Stat(targetPath); err != nil {
+					return fmt.Errorf("error opening GeoPackage: %w", err)
+				}
+				findingsCount += checkGeopackage(targetPath, tmID)
+			}
+			if findingsCount > 0 {
+				return fmt.Errorf("found %d invalid geometries", findingsCount)
+			}
+			slog.Info("no invalid geometries found")
+			return nil
+		},
+	}
+}
+
+// checkGeopackage logs the findings for all tables in a GPKG and returns the count
+func checkGeopackage(path string, tmID tms20.TMID) int {
+	source := gpkg.SourceGeopackage{}
+	source.Init(path)
+	defer source.Close()
+
+	findingsCount := 0
+	for _, table := range source.GetTableInfo() {
+		source.Table = table
+		features := make(chan processing.Feature)
+		go source.ReadFeatures(features)
+		for feature := range features {
+			for _, finding := range validate.Geometry(feature.Geometry()) {
+				finding.FID = feature.FID()
+				finding.TileMatrixID = tmID
+				slog.Warn("invalid geometry", "table", table.Name, "fid", finding.FID, "tmID", finding.TileMatrixID,
+					"kind", finding.Kind, "location", finding.Location)
+				findingsCount++
+			}
+		}
+	}
+	return findingsCount
+}
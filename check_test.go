@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/tms20"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSnapped(t *testing.T) {
+	square := geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}
+	bowTie := geom.Polygon{{{0, 0}, {4, 4}, {4, 0}, {0, 4}}}
+	tests := []struct {
+		name         string
+		mode         validateMode
+		want         map[tms20.TMID][]geom.Polygon
+		wantFindings int
+		wantErr      bool
+	}{
+		{name: "off", mode: validateOff, want: map[tms20.TMID][]geom.Polygon{5: {bowTie}, 6: {square}}},
+		{name: "fail", mode: validateFail, wantFindings: 1, wantErr: true},
+		{name: "quarantine", mode: validateQuarantine, want: map[tms20.TMID][]geom.Polygon{6: {square}}, wantFindings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPolygonsPerTileMatrix := map[tms20.TMID][]geom.Polygon{5: {bowTie}, 6: {square}}
+
+			got, findings, err := validateSnapped(42, newPolygonsPerTileMatrix, tt.mode)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			require.Len(t, findings, tt.wantFindings)
+			for _, finding := range findings {
+				assert.Equal(t, int64(42), finding.FID)
+				assert.Equal(t, tms20.TMID(5), finding.TileMatrixID)
+			}
+			assert.Equal(t, map[tms20.TMID][]geom.Polygon{5: {bowTie}, 6: {square}}, newPolygonsPerTileMatrix, "the argument is left as is")
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+	"github.com/pdok/texel/validate"
+)
+
+// debugOptions are for dumping the intermediate stages of snapping, for the listed features or those that fail
+type debugOptions struct {
+	dir       string
+	fids      map[int64]any
+	onFailure bool
+}
+
+func (o debugOptions) listed(fid int64) bool {
+	_, ok := o.fids[fid]
+	return ok
+}
+
+// writeDump writes the stages of a feature (with the reason it failed, if it did) to its directory in the debug directory
+func (o debugOptions) writeDump(tmsID string, tableName string, fid int64, debug *snap.Debug, failure string) {
+	dir := path.Join(o.dir, unsafeFileNameCharsRegex.ReplaceAllString(fmt.Sprintf("%s_%s_%d", tableName, tmsID, fid), "_"))
+	if err := writeDebug(dir, debug, failure); err != nil {
+		slog.Warn("could not write the debug dump", "tms", tmsID, "table", tableName, "fid", fid, "error", err)
+		return
+	}
+	slog.Info("wrote the snapping stages", "tms", tmsID, "table", tableName, "fid", fid, "dir", dir)
+}
+
+// dumpFeature snaps the (polygons of the) feature again, now collecting the stages of all of them,
+// and writes them (with the reason it failed, if it did) if the feature is listed or failed.
+// Snapping is deterministic, so these are the stages of the run (up to the panic, if it panicked).
+func (o debugOptions) dumpFeature(fid int64, geometry geom.Geometry, tms tms20.TileMatrixSet, tmIDs []tms20.TMID, tableName string,
+	snapConfig snap.Config, failure string) {
+	if !o.listed(fid) && (failure == "" || !o.onFailure) {
+		return
+	}
+	var polygons []geom.Polygon
+	switch geometry := geometry.(type) {
+	case geom.Polygon:
+		polygons = []geom.Polygon{geometry}
+	case geom.MultiPolygon:
+		for _, polygon := range geometry {
+			polygons = append(polygons, polygon)
+		}
+	}
+	snapConfig.Debug = &snap.Debug{}
+	snapConfig.Stats = nil                                             // counted in the run already
+	snapConfig.Logger = slog.New(slog.NewTextHandler(io.Discard, nil)) // logged in the run already
+	func() {
+		defer func() {
+			_ = recover() // the same panic, reported by the run
+		}()
+		for _, polygon := range polygons {
+			snapFeature(fid, polygon, tms, tmIDs, tableName, snapConfig, nil)
+		}
+	}()
+	o.writeDump(tms.ID, tableName, fid, snapConfig.Debug, failure)
+}
+
+// failureOf describes why snapping a feature failed, empty if it didn't
+func failureOf(findings []validate.Finding, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if len(findings) > 0 {
+		return fmt.Sprintf("quarantined: %v", findings)
+	}
+	return ""
+}
+
+// writeDebug writes every stage to a numbered file in the (cleared) directory, the geometries as GeoJSON and the point index as WKT
+func writeDebug(dir string, debug *snap.Debug, failure string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if failure != "" {
+		if err := os.WriteFile(path.Join(dir, "failure.txt"), []byte(failure+"\n"), 0o644); err != nil { //nolint:gosec
+			return err
+		}
+	}
+	for i, stage := range debug.Stages {
+		name := fmt.Sprintf("%03d_%s", i, strings.ReplaceAll(stage.Name, " ", "_"))
+		if stage.Geometry == nil {
+			if err := os.WriteFile(path.Join(dir, name+".wkt"), []byte(stage.WKT), 0o644); err != nil { //nolint:gosec
+				return err
+			}
+			continue
+		}
+		geoJSON, err := json.Marshal(geojson.Geometry{Geometry: stage.Geometry})
+		if err != nil {
+			return fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+		if err = os.WriteFile(path.Join(dir, name+".geojson"), geoJSON, 0o644); err != nil { //nolint:gosec
+			return err
+		}
+	}
+	return nil
+}
@@ -156,3 +156,121 @@ func wktMustEncodeTruncated(geom geom.Geometry, width uint) string {
 	}
 	return truncate.StringWithTail(wkt.MustEncode(geom), width, "...")
 }
+
+// from paulmach/orb, modified to also return whether it's on the boundary
+// RingContains returns true if the point is inside the ring.
+// Points on the boundary are also considered in. In which case the second returned var is true too.
+func RingContains(ring [][2]float64, point [2]float64) (contains, onBoundary bool) {
+	// TODO check first if the point is in the extent/bound/envelop
+
+	c, on := RayIntersect(point, ring[0], ring[len(ring)-1])
+	if on {
+		return true, true
+	}
+
+	for i := 0; i < len(ring)-1; i++ {
+		intersects, on := RayIntersect(point, ring[i], ring[i+1])
+		if on {
+			return true, true
+		}
+
+		if intersects {
+			c = !c // https://en.wikipedia.org/wiki/Even-odd_rule
+		}
+	}
+
+	return c, false
+}
+
+// Orientation returns the sign of the cross product of (b - a) and (c - a):
+// 1 if a, b, c make a counter-clockwise turn, -1 if clockwise and 0 if collinear
+func Orientation(a, b, c [2]float64) int {
+	cross := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	switch {
+	case cross > 0:
+		return 1
+	case cross < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// SegmentsCross returns whether segments ab and cd properly cross each other,
+// meaning they share exactly one point that lies in the interior of both segments.
+// Touching (at an end point) and overlapping (collinear) segments do not cross.
+// If they cross, the intersection point is returned too.
+func SegmentsCross(a, b, c, d [2]float64) ([2]float64, bool) {
+	o1 := Orientation(a, b, c)
+	o2 := Orientation(a, b, d)
+	o3 := Orientation(c, d, a)
+	o4 := Orientation(c, d, b)
+	if o1*o2 >= 0 || o3*o4 >= 0 {
+		return [2]float64{}, false
+	}
+	denominator := (a[0]-b[0])*(c[1]-d[1]) - (a[1]-b[1])*(c[0]-d[0])
+	t := ((a[0]-c[0])*(c[1]-d[1]) - (a[1]-c[1])*(c[0]-d[0])) / denominator
+	return [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}, true
+}
+
+// ClipPolygon clips the rings of a polygon to an extent (Sutherland–Hodgman).
+// Rings with less than three points left are removed, nil is returned if that is the outer ring.
+// A concave ring can get degenerate edges along the extent's boundary, which snapping cleans up.
+func ClipPolygon(polygon geom.Polygon, extent geom.Extent) geom.Polygon {
+	var clipped geom.Polygon
+	for ringIdx, ring := range polygon {
+		clippedRing := clipRing(ring, extent)
+		if len(clippedRing) < 3 {
+			if ringIdx == 0 {
+				return nil
+			}
+			continue
+		}
+		clipped = append(clipped, clippedRing)
+	}
+	return clipped
+}
+
+// clipRing clips a ring to each of the extent's edges in turn
+func clipRing(ring [][2]float64, extent geom.Extent) [][2]float64 {
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	edges := [4]struct {
+		axis  int
+		value float64
+		isMin bool
+	}{{0, extent.MinX(), true}, {1, extent.MinY(), true}, {0, extent.MaxX(), false}, {1, extent.MaxY(), false}}
+	for _, edge := range edges {
+		inside := func(pt [2]float64) bool {
+			if edge.isMin {
+				return pt[edge.axis] >= edge.value
+			}
+			return pt[edge.axis] <= edge.value
+		}
+		intersection := func(a, b [2]float64) [2]float64 {
+			t := (edge.value - a[edge.axis]) / (b[edge.axis] - a[edge.axis])
+			pt := [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+			pt[edge.axis] = edge.value // exactly on the edge
+			return pt
+		}
+		input := ring
+		ring = make([][2]float64, 0, len(input)+4)
+		for i, current := range input {
+			previous := input[(i+len(input)-1)%len(input)]
+			switch {
+			case inside(current):
+				if !inside(previous) {
+					ring = append(ring, intersection(previous, current))
+				}
+				ring = append(ring, current)
+			case inside(previous):
+				ring = append(ring, intersection(previous, current))
+			}
+		}
+		if len(ring) == 0 {
+			break
+		}
+	}
+	return ring
+}
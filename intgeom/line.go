@@ -1,7 +1,7 @@
 package intgeom
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/go-spatial/geom"
 )
@@ -48,8 +48,8 @@ func (l Line) ContainsPoint(pt [2]M) bool {
 		miny, maxy = maxy, miny
 	}
 	if debug {
-		log.Printf("pt.x %v is between %v and %v: %v && %v", pt[0], minx, maxx, minx <= pt[0], pt[0] <= maxx)
-		log.Printf("pt.y %v is between %v and %v: %v && %v", pt[1], miny, maxy, miny <= pt[1], pt[1] <= maxy)
+		slog.Debug("pt.x is between minx and maxx", "x", pt[0], "minx", minx, "maxx", maxx, "between", minx <= pt[0] && pt[0] <= maxx)
+		slog.Debug("pt.y is between miny and maxy", "y", pt[1], "miny", miny, "maxy", maxy, "between", miny <= pt[1] && pt[1] <= maxy)
 	}
 
 	return minx <= pt[0] && pt[0] <= maxx && miny <= pt[1] && pt[1] <= maxy
@@ -0,0 +1,42 @@
+package loghelp
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// Setup makes a logger with the level (debug, info, warn or error) and format the default one.
+// What is still logged with the standard log package goes through it too (at info level).
+func Setup(w io.Writer, level string, format string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf(`unknown log level "%s", should be "debug", "info", "warn" or "error"`, level)
+	}
+	options := &slog.HandlerOptions{Level: l}
+	var handler slog.Handler
+	switch Format(format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf(`unknown log format "%s", should be "%s" or "%s"`, format, FormatText, FormatJSON)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Fatal logs at error level and exits, like log.Fatal
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
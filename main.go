@@ -4,16 +4,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path"
-	"slices"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/pdok/texel/loghelp"
+	"github.com/pdok/texel/mapslicehelp"
 	"github.com/pdok/texel/pointindex"
 
-	"github.com/go-spatial/geom"
-
 	"github.com/carlmjohnson/versioninfo"
 
 	"github.com/pdok/texel/processing"
@@ -33,7 +34,26 @@ const TILEMATRICES string = `tilematrices`
 const PAGESIZE string = `pagesize`
 const KEEPPOINTSANDLINES string = `keeppointsandlines`
 const IGNOREOUTSIDEGRID string = `ignoreoutsidegrid`
+const CLIPOUTSIDEGRID string = `clipoutsidegrid`
+const REPAIR string = `repair`
 const REVERSEWINDINGORDER string = `reversewindingorder`
+const VALIDATE string = `validate`
+const INTERNALPIXELRESOLUTION string = `internalpixelresolution`
+const INTERNALPIXELRESOLUTIONS string = `internalpixelresolutions`
+const MINOUTERAREAS string = `minouterareas`
+const MININNERAREAS string = `mininnerareas`
+const SIMPLIFY string = `simplify`
+const SRSMISMATCH string = `srsmismatch`
+const TILEMATRIXSETS string = `tilematrixsets`
+const DEBUGFIDS string = `debugfids`
+const DEBUGONFAILURE string = `debugonfailure`
+const DEBUGDIR string = `debugdir`
+const REPRODIR string = `reprodir`
+const REPORT string = `report`
+const LOGLEVEL string = `loglevel`
+const LOGFORMAT string = `logformat`
+const PROGRESS string = `progress`
+const PROGRESSINTERVAL string = `progressinterval`
 
 //nolint:funlen
 func main() {
@@ -41,20 +61,21 @@ func main() {
 	app.Name = "texel"
 	app.Usage = "A Golang Polygon Snapping application"
 	app.Version = versioninfo.Short()
+	app.DisableSliceFlagSeparator = true // the tile matrices in the tile matrix set pairs contain commas
 
 	app.Flags = []cli.Flag{
 		&cli.StringFlag{
 			Name:     SOURCE,
 			Aliases:  []string{"s"},
 			Usage:    "Source GPKG",
-			Required: true,
+			Required: false, // checked in app.Action, otherwise also required for the subcommands
 			EnvVars:  []string{strcase.ToScreamingSnake(SOURCE)},
 		},
 		&cli.StringFlag{
 			Name:     TARGET,
 			Aliases:  []string{"t"},
 			Usage:    "Target GPKG (prefix). One GPKG per tile matrix cq zoom level will be created and the filename will be suffixed. E.g. target_6.gpkg",
-			Required: true,
+			Required: false, // checked in app.Action, otherwise also required for the subcommands
 			EnvVars:  []string{strcase.ToScreamingSnake(TARGET)},
 		},
 		&cli.BoolFlag{
@@ -67,15 +88,22 @@ func main() {
 		&cli.StringFlag{
 			Name:     TILEMATRIXSET,
 			Aliases:  []string{"tms"},
-			Usage:    `ID of a (built-in) tile matrix set. E.g.: NetherlandsRDNewQuad`,
-			Required: true,
+			Usage:    `ID of a (built-in) tile matrix set, a tile matrix set JSON file or a WMTS capabilities XML file (with #<identifier> if it has multiple). E.g.: NetherlandsRDNewQuad or wmts.xml#EPSG:28992`,
+			Required: false, // checked in app.Action, otherwise also required for the subcommands
 			EnvVars:  []string{strcase.ToScreamingSnake(TILEMATRIXSET)},
 		},
+		&cli.StringSliceFlag{
+			Name:     TILEMATRIXSETS,
+			Aliases:  []string{"tmss"},
+			Usage:    `Tile matrix set with its tile matrices, as <tms>:<tile matrices> (see the tms and z flags), instead of the tms and z flags. Can be repeated to process multiple tile matrix sets in one run, then the ID of the tile matrix set is added to the target GPKG names. E.g.: -tmss='NetherlandsRDNewQuad:[5,6]' -tmss='WebMercatorQuad:auto'`,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(TILEMATRIXSETS)},
+		},
 		&cli.StringFlag{
 			Name:     TILEMATRICES,
 			Aliases:  []string{"z"},
-			Usage:    `IDs (usually the same as the zoom levels) of the tile matrices in the tile matrix set that should be processed for. JSON array of integers. E.g.: [4,5,6,7,8]`,
-			Required: true,
+			Usage:    `IDs (usually the same as the zoom levels) of the tile matrices in the tile matrix set that should be processed for. JSON array of integers. E.g.: [4,5,6,7,8]. Or a JSON object with a range of scale denominators and/or resolutions (minScaleDenominator, maxScaleDenominator, minResolution, maxResolution), e.g.: {"minScaleDenominator":10000,"maxScaleDenominator":1000000}. Or auto, for the tile matrices where snapping changes the source`,
+			Required: false, // checked in app.Action, otherwise also required for the subcommands
 			EnvVars:  []string{strcase.ToScreamingSnake(TILEMATRICES)},
 		},
 		&cli.IntFlag{
@@ -103,6 +131,22 @@ func main() {
 			Required: false,
 			EnvVars:  []string{strcase.ToScreamingSnake(IGNOREOUTSIDEGRID)},
 		},
+		&cli.BoolFlag{
+			Name:     CLIPOUTSIDEGRID,
+			Aliases:  []string{"cog"},
+			Usage:    "Clip polygons that fall (partly) outside the grid to the grid's extent, instead of panicking. The clipped features are reported at the end",
+			Value:    false,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(CLIPOUTSIDEGRID)},
+		},
+		&cli.BoolFlag{
+			Name:     REPAIR,
+			Aliases:  []string{"rep"},
+			Usage:    "Repair the polygons before snapping (repeated vertices, spikes, degenerate rings, self-intersections and holes outside the shell). The repaired features are reported at the end",
+			Value:    false,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(REPAIR)},
+		},
 		&cli.BoolFlag{
 			Name:     REVERSEWINDINGORDER,
 			Aliases:  []string{"rwo"},
@@ -111,93 +155,364 @@ func main() {
 			Required: false,
 			EnvVars:  []string{strcase.ToScreamingSnake(REVERSEWINDINGORDER)},
 		},
+		&cli.StringFlag{
+			Name:     VALIDATE,
+			Aliases:  []string{"val"},
+			Usage:    "Validate the snapped polygons. On an invalid result either 'fail' or 'quarantine' (leave the feature out of that tile matrix' target and log it)",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(VALIDATE)},
+		},
+		&cli.UintFlag{
+			Name:     INTERNALPIXELRESOLUTION,
+			Aliases:  []string{"ipr"},
+			Usage:    "Number of internal pixels (on one axis) a tile pixel is divided into when snapping. Must be a power of two",
+			Value:    pointindex.VectorTileInternalPixelResolution,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(INTERNALPIXELRESOLUTION)},
+		},
+		&cli.StringFlag{
+			Name:     INTERNALPIXELRESOLUTIONS,
+			Aliases:  []string{"iprs"},
+			Usage:    `Internal pixel resolution per tile matrix, overriding the one for the run. JSON object of tile matrix IDs to powers of two. E.g.: {"12":2,"13":2}`,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(INTERNALPIXELRESOLUTIONS)},
+		},
+		&cli.StringFlag{
+			Name:     MINOUTERAREAS,
+			Aliases:  []string{"moa"},
+			Usage:    `Minimal area of a (snapped) polygon per tile matrix, smaller polygons are removed. JSON object of tile matrix IDs to areas in tile pixels. E.g.: {"5":2,"6":1}`,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(MINOUTERAREAS)},
+		},
+		&cli.StringFlag{
+			Name:     MININNERAREAS,
+			Aliases:  []string{"mia"},
+			Usage:    `Minimal area of a (snapped) hole per tile matrix, smaller holes are filled. JSON object of tile matrix IDs to areas in tile pixels. E.g.: {"5":2,"6":1}`,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(MININNERAREAS)},
+		},
+		&cli.Float64Flag{
+			Name:     SIMPLIFY,
+			Aliases:  []string{"simp"},
+			Usage:    "Simplify the snapped rings, removing vertices within this distance (in internal pixels) of the shortcut between their neighbours, as long as no intersections arise. 0 is no simplification",
+			Value:    0,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(SIMPLIFY)},
+		},
+		&cli.StringFlag{
+			Name:     SRSMISMATCH,
+			Aliases:  []string{"srsm"},
+			Usage:    "What to do with source tables of which the SRS does not match the CRS of the tile matrix set: 'reproject' (if supported, otherwise fail), 'fail' or 'skip' (leave the table out and list it at the end). Tables without polygons are copied as is on 'fail' or 'skip'",
+			Value:    string(srsMismatchReproject),
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(SRSMISMATCH)},
+		},
+		&cli.StringFlag{
+			Name:     DEBUGFIDS,
+			Aliases:  []string{"dfid"},
+			Usage:    "Write the intermediate stages of snapping these features to the debug dir. JSON array of fids. E.g.: [12,345]",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(DEBUGFIDS)},
+		},
+		&cli.BoolFlag{
+			Name:     DEBUGONFAILURE,
+			Aliases:  []string{"dof"},
+			Usage:    "Write the intermediate stages of snapping to the debug dir for features that fail (panic or are invalid)",
+			Value:    false,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(DEBUGONFAILURE)},
+		},
+		&cli.StringFlag{
+			Name:     DEBUGDIR,
+			Aliases:  []string{"dd"},
+			Usage:    "Directory to write the intermediate stages of snapping to, a subdirectory per feature",
+			Value:    "debug",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(DEBUGDIR)},
+		},
+		&cli.StringFlag{
+			Name:     REPRODIR,
+			Aliases:  []string{"rd"},
+			Usage:    "Directory to write a reproduction of a panic while snapping to, for replaying it with: texel repro <file>",
+			Value:    ".",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(REPRODIR)},
+		},
+		&cli.StringFlag{
+			Name:     REPORT,
+			Aliases:  []string{"rp"},
+			Usage:    "JSON file to write the report of the run to: the figures per table and tile matrix, the wall time per stage and the deviation of the deepest tile matrix",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(REPORT)},
+		},
+		&cli.StringFlag{
+			Name:     LOGLEVEL,
+			Aliases:  []string{"ll"},
+			Usage:    "Minimal level of the log messages: 'debug', 'info', 'warn' or 'error'",
+			Value:    "info",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(LOGLEVEL)},
+		},
+		&cli.StringFlag{
+			Name:     LOGFORMAT,
+			Aliases:  []string{"lf"},
+			Usage:    "Format of the log messages: 'text' (key=value) or 'json' (a JSON object per line)",
+			Value:    string(loghelp.FormatText),
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(LOGFORMAT)},
+		},
+		&cli.StringFlag{
+			Name:     PROGRESS,
+			Aliases:  []string{"pr"},
+			Usage:    "Report the progress of snapping every table (features processed, rate, ETA and memory use): 'interactive' (a line that is redrawn), 'log' (a log message every progress interval), 'off' or 'auto' (interactive on a terminal, log otherwise)",
+			Value:    string(progressAuto),
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(PROGRESS)},
+		},
+		&cli.DurationFlag{
+			Name:     PROGRESSINTERVAL,
+			Aliases:  []string{"pri"},
+			Usage:    "Interval of the progress log messages. E.g.: 30s or 5m",
+			Value:    time.Minute,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(PROGRESSINTERVAL)},
+		},
+	}
+
+	app.Before = func(c *cli.Context) error {
+		return loghelp.Setup(os.Stderr, c.String(LOGLEVEL), c.String(LOGFORMAT))
+	}
+
+	app.Commands = []*cli.Command{
+		checkCommand(),
+		reproCommand(),
+		snapGeomCommand(),
+		svgCommand(),
+		tmsCommand(),
 	}
 
 	app.Action = func(c *cli.Context) error {
-		tileMatrixSet, err := tms20.LoadEmbeddedTileMatrixSet(c.String(TILEMATRIXSET))
+		if err := checkRequiredFlags(c, SOURCE, TARGET); err != nil {
+			return err
+		}
+		report := newRunReport()
+		report.startStage("setup")
+		validateMode, err := parseValidateMode(c.String(VALIDATE))
+		if err != nil {
+			return err
+		}
+		srsMismatchMode, err := parseSRSMismatchMode(c.String(SRSMISMATCH))
 		if err != nil {
 			return err
 		}
-		var tileMatrixIDs []int
-		err = json.Unmarshal([]byte(c.String(TILEMATRICES)), &tileMatrixIDs)
+		progressMode, err := parseProgressMode(c.String(PROGRESS))
 		if err != nil {
 			return err
 		}
-		if err = validateTileMatrixSet(tileMatrixSet, tileMatrixIDs); err != nil {
+		progressInterval := c.Duration(PROGRESSINTERVAL)
+		if progressInterval <= 0 {
+			return fmt.Errorf(`flag "%s" should be positive, not %s`, PROGRESSINTERVAL, progressInterval)
+		}
+		runs, err := parseTileMatrixSetRuns(c)
+		if err != nil {
+			return err
+		}
+		snapConfig := snap.Config{
+			KeepPointsAndLines:      c.Bool(KEEPPOINTSANDLINES),
+			IgnoreOutsideGrid:       c.Bool(IGNOREOUTSIDEGRID),
+			ClipOutsideGrid:         c.Bool(CLIPOUTSIDEGRID),
+			ReverseWindingOrder:     c.Bool(REVERSEWINDINGORDER),
+			InternalPixelResolution: c.Uint(INTERNALPIXELRESOLUTION),
+			SimplifyTolerance:       c.Float64(SIMPLIFY),
+		}
+		if c.IsSet(INTERNALPIXELRESOLUTIONS) {
+			if err = json.Unmarshal([]byte(c.String(INTERNALPIXELRESOLUTIONS)), &snapConfig.InternalPixelResolutions); err != nil {
+				return err
+			}
+		}
+		if c.IsSet(MINOUTERAREAS) {
+			if err = json.Unmarshal([]byte(c.String(MINOUTERAREAS)), &snapConfig.MinOuterAreas); err != nil {
+				return err
+			}
+		}
+		if c.IsSet(MININNERAREAS) {
+			if err = json.Unmarshal([]byte(c.String(MININNERAREAS)), &snapConfig.MinInnerAreas); err != nil {
+				return err
+			}
+		}
+		if err = snapConfig.Validate(); err != nil {
 			return err
 		}
+		debug := debugOptions{dir: c.String(DEBUGDIR), onFailure: c.Bool(DEBUGONFAILURE)}
+		if c.IsSet(DEBUGFIDS) {
+			var fids []int64
+			if err = json.Unmarshal([]byte(c.String(DEBUGFIDS)), &fids); err != nil {
+				return fmt.Errorf("could not parse %s: %w", DEBUGFIDS, err)
+			}
+			debug.fids = mapslicehelp.AsKeys(fids)
+		}
 
 		_, err = os.Stat(c.String(SOURCE))
 		if os.IsNotExist(err) {
-			log.Fatalf("error opening source GeoPackage: %s", err)
+			loghelp.Fatal("error opening source GeoPackage", "error", err)
 		}
 
 		source := gpkg.SourceGeopackage{}
 		source.Init(c.String(SOURCE))
 		defer source.Close()
+		tables := source.GetTableInfo()
 
-		targetPathFmt := injectSuffixIntoPath(c.String(TARGET))
-
-		gpkgTargets := make(map[int]*gpkg.TargetGeopackage, len(tileMatrixIDs))
 		overwrite := c.Bool(OVERWRITE)
 		pagesize := c.Int(PAGESIZE) // TODO divide by tile matrices count
-		snapConfig := snap.Config{
-			KeepPointsAndLines:  c.Bool(KEEPPOINTSANDLINES),
-			IgnoreOutsideGrid:   c.Bool(IGNOREOUTSIDEGRID),
-			ReverseWindingOrder: c.Bool(REVERSEWINDINGORDER),
-		}
-		for _, tmID := range tileMatrixIDs {
-			gpkgTargets[tmID] = initGPKGTarget(targetPathFmt, tmID, overwrite, pagesize)
-			defer gpkgTargets[tmID].Close() // yes, supposed to go here, want to close all at end of func
-		}
-
-		tables := source.GetTableInfo()
-		for _, target := range gpkgTargets {
-			err = target.CreateTables(tables)
+		for _, run := range runs {
+			runTables, srsMismatchedTables, err := checkTablesSRS(tables, run.tms, srsMismatchMode)
+			if err != nil {
+				return err
+			}
+			run.tables = make(map[string]sourceTable, len(runTables))
+			for _, table := range runTables {
+				run.tables[table.Name] = table
+			}
+			if run.autoTileMatrixIDs {
+				if run.tileMatrixIDs, err = autoSelectTileMatrices(source, runTables, run.tms, snapConfig); err != nil {
+					return err
+				}
+			}
+			if err = validateTileMatrixSet(run.tms, run.tileMatrixIDs, snapConfig); err != nil {
+				return err
+			}
+			deviation, err := newDeviationReport(run.tms, run.tileMatrixIDs, snapConfig)
 			if err != nil {
-				log.Fatalf("error initialization the target GeoPackage: %s", err)
+				return err
+			}
+			report.addTileMatrixSet(run.tms.ID, deviation, srsMismatchedTables)
+
+			targetPathFmt := run.targetPathFmt(c.String(TARGET), len(runs) > 1)
+			run.targets = make(map[tms20.TMID]*gpkg.TargetGeopackage, len(run.tileMatrixIDs))
+			for _, tmID := range run.tileMatrixIDs {
+				run.targets[tmID] = initGPKGTarget(targetPathFmt, tmID, overwrite, pagesize)
+				defer run.targets[tmID].Close() // yes, supposed to go here, want to close all at end of func
+			}
+			for _, target := range run.targets {
+				err = target.CreateTables(targetTables(runTables))
+				if err != nil {
+					loghelp.Fatal("error initializing the target GeoPackage", "error", err)
+				}
 			}
 		}
 
-		log.Println("=== start snapping ===")
+		slog.Info("start snapping")
+		options := processingOptions{snapConfig: snapConfig, validateMode: validateMode, debug: debug, reproDir: c.String(REPRODIR)}
 
-		// need a copied map because of type difference processing.Target vs gpkg.TargetGeopackage
-		targets := make(map[int]processing.Target, len(gpkgTargets))
-		for tmID, target := range gpkgTargets {
-			targets[tmID] = target
-		}
-		// Process the tables sequentially
+		// Process the tables sequentially, each read once for all tile matrix sets
 		for _, table := range tables {
-			log.Printf("  snapping %s", table.Name)
-			for _, target := range gpkgTargets {
-				source.Table = table
-				target.Table = table
+			var tileMatrixSets []processing.TileMatrixSetProcessing
+			tileMatrixIDsByTMS := make(map[string][]tms20.TMID)
+			for _, run := range runs {
+				if p, ok := run.processing(table.Name, options, report); ok {
+					tileMatrixSets = append(tileMatrixSets, p)
+					tileMatrixIDsByTMS[run.tms.ID] = run.tileMatrixIDs
+				}
+			}
+			if len(tileMatrixSets) == 0 {
+				continue
+			}
+			slog.Info("snapping", "stage", "snapping", "table", table.Name)
+			report.startStage("snapping " + table.Name)
+			source.Table = table
+			var total int64
+			if progressMode != progressOff {
+				if total, err = source.CountFeatures(); err != nil {
+					slog.Warn("could not count the features, the progress has no ETA", "table", table.Name, "error", err)
+				}
 			}
-			processBySnapping(source, targets, tileMatrixSet, snapConfig)
-			log.Printf("  finished %s", table.Name)
+			var tableSource processing.Source = source
+			if c.Bool(REPAIR) {
+				tableSource = repairSource(tableSource, table.Name, report)
+			}
+			p := startProgress(progressMode, progressInterval, table.Name, total)
+			counts := processing.ProcessFeaturesForTileMatrixSets(p.countSource(tableSource), tileMatrixSets)
+			p.finish()
+			report.addCounts(table.Name, tileMatrixIDsByTMS, counts)
+			slog.Info("finished", "stage", "snapping", "table", table.Name, "features", counts.FeaturesIn,
+				"nonPolygons", counts.NonPolygons, "multiPolygons", counts.MultiPolygons, "kept", counts.FeaturesKept)
 		}
 
-		log.Println("=== done snapping ===")
+		slog.Info("done snapping")
+		report.finish()
+		report.log()
+		if c.IsSet(REPORT) {
+			if err = report.write(c.String(REPORT)); err != nil {
+				return fmt.Errorf("could not write the report: %w", err)
+			}
+		}
 		return nil
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		loghelp.Fatal(err.Error())
+	}
+}
+
+// parseTileMatrixSetRuns parses either the tile matrix set pairs, or the single tile matrix set with its tile matrices
+func parseTileMatrixSetRuns(c *cli.Context) ([]*tileMatrixSetRun, error) {
+	if c.IsSet(TILEMATRIXSETS) {
+		if c.IsSet(TILEMATRIXSET) || c.IsSet(TILEMATRICES) {
+			return nil, fmt.Errorf(`flag "%s" can not be combined with "%s" or "%s"`, TILEMATRIXSETS, TILEMATRIXSET, TILEMATRICES)
+		}
+		var runs []*tileMatrixSetRun
+		for _, pair := range c.StringSlice(TILEMATRIXSETS) {
+			run, err := parseTileMatrixSetPair(pair)
+			if err != nil {
+				return nil, err
+			}
+			for _, other := range runs {
+				if other.tms.ID == run.tms.ID {
+					return nil, fmt.Errorf(`tile matrix set %s is given more than once`, run.tms.ID)
+				}
+			}
+			runs = append(runs, run)
+		}
+		return runs, nil
+	}
+	if err := checkRequiredFlags(c, TILEMATRIXSET, TILEMATRICES); err != nil {
+		return nil, err
+	}
+	run, err := newTileMatrixSetRun(c.String(TILEMATRIXSET), c.String(TILEMATRICES))
+	if err != nil {
+		return nil, err
 	}
+	return []*tileMatrixSetRun{run}, nil
 }
 
-func validateTileMatrixSet(tms tms20.TileMatrixSet, tileMatrixIDs []tms20.TMID) error {
-	deepestTMID := slices.Max(tileMatrixIDs)
-	stats, deviationInUnits, deviationInPixels, err := pointindex.DeviationStats(tms, deepestTMID)
+func checkRequiredFlags(c *cli.Context, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if !c.IsSet(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf(`required flags "%s" not set`, strings.Join(missing, `", "`))
+	}
+	return nil
+}
+
+func validateTileMatrixSet(tms tms20.TileMatrixSet, tileMatrixIDs []tms20.TMID, snapConfig snap.Config) error {
+	deviation, err := newDeviationReport(tms, tileMatrixIDs, snapConfig)
 	if err != nil {
 		return err
 	}
-	if deviationInPixels >= 1 {
-		log.Printf("[WARNING] (largest) deviation is larger than 1 tile pixel (%f units) on the deepest matrix (%d)\n", deviationInUnits, deepestTMID)
-		log.Println(stats)
+	if deviation.InPixels >= 1 {
+		slog.Warn("(largest) deviation is larger than 1 tile pixel on the deepest matrix",
+			"tms", tms.ID, "tmID", deviation.TileMatrixID, "internalPixelResolution", deviation.InternalPixelResolution,
+			"inUnits", deviation.InUnits, "inPixels", deviation.InPixels)
 	}
-	return pointindex.IsQuadTree(tms)
+	if err = pointindex.IsQuadTree(tms); err != nil {
+		slog.Info("tile matrix set is not a quad tree, the tile matrices will be snapped independently", "tms", tms.ID, "reason", err)
+	}
+	return nil
 }
 
 func initGPKGTarget(targetPathFmt string, tmID int, overwrite bool, pagesize int) *gpkg.TargetGeopackage {
@@ -207,7 +522,7 @@ func initGPKGTarget(targetPathFmt string, tmID int, overwrite bool, pagesize int
 		var pathError *os.PathError
 		if err != nil {
 			if !(errors.As(err, &pathError) && errors.Is(pathError.Err, syscall.ENOENT)) {
-				log.Fatalf("could not remove target file: %e", err)
+				loghelp.Fatal("could not remove target file", "error", err)
 			}
 		}
 	}
@@ -222,9 +537,3 @@ func injectSuffixIntoPath(p string) string {
 	name := file[:len(file)-len(ext)]
 	return path.Join(dir, name+"_%v"+ext)
 }
-
-func processBySnapping(source processing.Source, targets map[tms20.TMID]processing.Target, tileMatrixSet tms20.TileMatrixSet, snapConfig snap.Config) {
-	processing.ProcessFeatures(source, targets, func(p geom.Polygon, tmIDs []tms20.TMID) map[tms20.TMID][]geom.Polygon {
-		return snap.SnapPolygon(p, tileMatrixSet, tmIDs, snapConfig)
-	})
-}
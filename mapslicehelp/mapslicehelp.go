@@ -25,6 +25,16 @@ func AsKeys[T constraints.Ordered](elements []T) map[T]any {
 	return mapped
 }
 
+// SortedKeys returns the keys of a map in ascending order, for iterating it deterministically
+func SortedKeys[K constraints.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 func FindLastKeyWithMaxValue[K comparable, V constraints.Ordered](m *orderedmap.OrderedMap[K, V]) (maxK K, maxV V, numWinners uint) {
 	first := true
 	for p := m.Newest(); p != nil; p = p.Prev() {
@@ -18,6 +18,10 @@ func Pow2(n uint) uint {
 	return 1 << n
 }
 
+func IsPow2(n uint) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
 func Bool2int(b bool) int {
 	if b {
 		return 1
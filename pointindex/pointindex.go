@@ -1,10 +1,12 @@
 package pointindex
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/bits"
 	"slices"
 	"strconv"
 
@@ -20,6 +22,8 @@ import (
 )
 
 const (
+	// VectorTileInternalPixelResolution is the default number of internal pixels (on one axis) a tile pixel is divided into.
+	// E.g. a 256px tile then has an internal pixel grid (cq extent) of 4096.
 	VectorTileInternalPixelResolution = 16
 )
 
@@ -68,19 +72,24 @@ type PointIndex struct {
 	// Number of quadrants (in one direction) on the deepest level (= 2 ^ deepestLevel)
 	deepestSize uint
 	deepestRes  intgeom.M
-	quadrants   map[Level]map[morton.Z]Quadrant
-	hitOnce     map[Level]map[intgeom.Point][]int
-	hitMultiple map[Level]map[intgeom.Point][]int
+	// Number of quadrants on the deepest level that fall inside the grid (on the x and y axis),
+	// if it is smaller than the (square) root extent. Zero otherwise.
+	deepestWidth  uint
+	deepestHeight uint
+	quadrants     map[Level]map[morton.Z]Quadrant
+	hitOnce       map[Level]map[intgeom.Point][]int
+	hitMultiple   map[Level]map[intgeom.Point][]int
 }
 
 type Level = uint
 type Q = int // quadrant index (0, 1, 2 or 3)
 
-func FromTileMatrixSet(tileMatrixSet tms20.TileMatrixSet, deepestTMID tms20.TMID) (*PointIndex, error) {
+// FromTileMatrixSet creates a PointIndex for a (quad tree) tile matrix set.
+// The deepest level is the internal pixel grid of the deepest tile matrix, with the given internal pixel resolution.
+// Variable matrix widths are ignored, coalesced tiles are snapped to the (finer) grid of the regular tiles.
+func FromTileMatrixSet(tileMatrixSet tms20.TileMatrixSet, deepestTMID tms20.TMID, internalPixelResolution uint) (*PointIndex, error) {
 	// assuming IsQuadTree was tested before
-	rootTM := tileMatrixSet.TileMatrices[0]
-	levelDiff := uint(math.Log2(float64(rootTM.TileWidth))) + uint(math.Log2(float64(VectorTileInternalPixelResolution)))
-	deepestLevel := uint(deepestTMID) + levelDiff
+	deepestLevel := LevelForTileMatrix(tileMatrixSet, deepestTMID, internalPixelResolution)
 	bottomLeft, topRight, err := tileMatrixSet.MatrixBoundingBox(0)
 	if err != nil {
 		return nil, fmt.Errorf(`could not make PointIndex from TileMatrixSet %v: %w'`, tileMatrixSet.ID, err)
@@ -106,6 +115,90 @@ func FromTileMatrixSet(tileMatrixSet tms20.TileMatrixSet, deepestTMID tms20.TMID
 	return &ix, nil
 }
 
+// FromTileMatrix creates a PointIndex for a single tile matrix, with its own grid origin and cell size.
+// So the tile matrix set doesn't need to be a quad tree.
+// The deepest level is the internal pixel grid of the tile matrix, with the given internal pixel resolution.
+// The root extent is the smallest square of 2^n internal pixels covering the tile matrix, starting at its bottom left.
+// Variable matrix widths are ignored, coalesced tiles are snapped to the (finer) grid of the regular tiles.
+func FromTileMatrix(tileMatrixSet tms20.TileMatrixSet, tmID tms20.TMID, internalPixelResolution uint) (*PointIndex, error) {
+	tm, ok := tileMatrixSet.TileMatrices[tmID]
+	if !ok {
+		return nil, fmt.Errorf(`could not make PointIndex from TileMatrixSet %v: tile matrix with id %v not found`, tileMatrixSet.ID, tmID)
+	}
+	pointOfOrigin, err := tms20.ToXYPoint(&tileMatrixSet, *tm.PointOfOrigin)
+	if err != nil {
+		return nil, fmt.Errorf(`could not make PointIndex from TileMatrixSet %v: %w'`, tileMatrixSet.ID, err)
+	}
+	deepestWidth := tm.MatrixWidth * tm.TileWidth * internalPixelResolution
+	deepestHeight := tm.MatrixHeight * tm.TileHeight * internalPixelResolution
+	deepestLevel := Level(bits.Len(max(deepestWidth, deepestHeight) - 1)) // ceil(log2)
+	deepestSize := mathhelp.Pow2(deepestLevel)
+	deepestRes := intgeom.FromGeomOrd(tm.CellSize / float64(internalPixelResolution))
+
+	intMinX := intgeom.FromGeomOrd(pointOfOrigin.X())
+	var intMinY intgeom.M
+	switch tm.CornerOfOrigin {
+	case tms20.BottomLeft:
+		intMinY = intgeom.FromGeomOrd(pointOfOrigin.Y())
+	default:
+		intMinY = intgeom.FromGeomOrd(pointOfOrigin.Y()) - int64(deepestHeight)*deepestRes
+	}
+	intSpan := int64(deepestSize) * deepestRes
+	intExtent := intgeom.Extent{intMinX, intMinY, intMinX + intSpan, intMinY + intSpan}
+	ix := PointIndex{
+		Quadrant: Quadrant{
+			intExtent: intExtent,
+			z:         0,
+		},
+		deepestLevel:  deepestLevel,
+		deepestSize:   deepestSize,
+		deepestRes:    deepestRes,
+		deepestWidth:  deepestWidth,
+		deepestHeight: deepestHeight,
+		quadrants:     make(map[Level]map[morton.Z]Quadrant, deepestLevel+1),
+		hitOnce:       make(map[uint]map[intgeom.Point][]int),
+		hitMultiple:   make(map[uint]map[intgeom.Point][]int),
+	}
+	_, ix.intCentroid = ix.getQuadrantExtentAndCentroid(0, 0, 0, intExtent)
+
+	return &ix, nil
+}
+
+// DeepestLevel returns the level of the (internal pixel) grid that is snapped to
+func (ix *PointIndex) DeepestLevel() Level {
+	return ix.deepestLevel
+}
+
+// Resolution returns the size of a quadrant on a level (an internal pixel, if it's the level of a tile matrix)
+func (ix *PointIndex) Resolution(level Level) float64 {
+	return intgeom.ToGeomOrd(ix.deepestRes * intgeom.M(mathhelp.Pow2(ix.deepestLevel-level)))
+}
+
+// Points returns the (centroids of the) quadrants on a level that contain a point, sorted by y and x.
+// For drawing the points that are snapped to.
+func (ix *PointIndex) Points(level Level) [][2]float64 {
+	points := make([][2]float64, 0, len(ix.quadrants[level]))
+	for _, quadrant := range ix.quadrants[level] {
+		points = append(points, quadrant.intCentroid.ToGeomPoint())
+	}
+	slices.SortFunc(points, func(a, b [2]float64) int {
+		if a[yAx] != b[yAx] {
+			return cmp.Compare(a[yAx], b[yAx])
+		}
+		return cmp.Compare(a[xAx], b[xAx])
+	})
+	return points
+}
+
+// LevelForTileMatrix returns the level in a PointIndex (created from a quad tree tile matrix set)
+// that matches the internal pixel grid of a tile matrix, with the given internal pixel resolution
+func LevelForTileMatrix(tileMatrixSet tms20.TileMatrixSet, tmID tms20.TMID, internalPixelResolution uint) Level {
+	rootTM := tileMatrixSet.TileMatrices[0]
+	levelDiff := uint(math.Log2(float64(rootTM.TileWidth))) + uint(math.Log2(float64(internalPixelResolution)))
+	// assuming 2^(tmID) = tm.MatrixWidth = tm.MatrixHeight
+	return uint(tmID) + levelDiff
+}
+
 // InsertPolygon inserts all points from a Polygon
 func (ix *PointIndex) InsertPolygon(polygon geom.Polygon) error {
 	// initialize the quadrants map
@@ -139,22 +232,28 @@ func (ix *PointIndex) InsertPoint(point geom.Point) error {
 }
 
 type OutsideGridError struct {
-	deepestX    int
-	deepestY    int
-	deepestSize uint
+	deepestX      int
+	deepestY      int
+	deepestWidth  uint
+	deepestHeight uint
 }
 
 func (e OutsideGridError) Error() string {
-	return fmt.Sprintf("trying to insert a coord (%v, %v) outside the grid/extent (0, %v; 0, %v)", e.deepestX, e.deepestY, e.deepestSize, e.deepestSize)
+	return fmt.Sprintf("trying to insert a coord (%v, %v) outside the grid/extent (0, %v; 0, %v)", e.deepestX, e.deepestY, e.deepestWidth, e.deepestHeight)
 }
 
 // InsertCoord inserts a Point by its x/y coord on the deepest level
 func (ix *PointIndex) InsertCoord(deepestX int, deepestY int) error {
-	if deepestX < 0 || deepestY < 0 || deepestX > int(ix.deepestSize)-1 || deepestY > int(ix.deepestSize)-1 {
+	deepestWidth, deepestHeight := ix.deepestSize, ix.deepestSize
+	if ix.deepestWidth != 0 {
+		deepestWidth, deepestHeight = ix.deepestWidth, ix.deepestHeight
+	}
+	if deepestX < 0 || deepestY < 0 || deepestX > int(deepestWidth)-1 || deepestY > int(deepestHeight)-1 {
 		return OutsideGridError{
-			deepestX:    deepestX,
-			deepestY:    deepestY,
-			deepestSize: ix.deepestSize,
+			deepestX:      deepestX,
+			deepestY:      deepestY,
+			deepestWidth:  deepestWidth,
+			deepestHeight: deepestHeight,
 		}
 	}
 	ix.insertCoord(deepestX, deepestY)
@@ -227,6 +326,17 @@ func (ix *PointIndex) SnapClosestPoints(line geom.Line, levelMap map[Level]any,
 	return pointsPerLevel
 }
 
+// IntersectedPoints returns the points (centroids) in the index that are intersected by a line on a level.
+// Unlike SnapClosestPoints the hits are not registered.
+func (ix *PointIndex) IntersectedPoints(line geom.Line, level Level) [][2]float64 {
+	quadrants := ix.snapClosestPoints(intgeom.FromGeomLine(line), map[Level]any{level: nil})[level]
+	points := make([][2]float64, len(quadrants))
+	for i, quadrant := range quadrants {
+		points[i] = quadrant.intCentroid.ToGeomPoint()
+	}
+	return points
+}
+
 func (ix *PointIndex) snapClosestPoints(intLine intgeom.Line, levelMap map[Level]any) map[Level][]Quadrant {
 	if len(levelMap) == 0 || !lineIntersects(intLine, ix.intExtent) {
 		return nil
@@ -525,16 +635,6 @@ func IsQuadTree(tms tms20.TileMatrixSet) error {
 		if tm.TileHeight != tm.TileWidth {
 			return errors.New("tiles should be square: " + tm.ID)
 		}
-		tmIDStringToInt, err := strconv.Atoi(tm.ID)
-		if err != nil {
-			return err
-		}
-		if tmIDStringToInt != tmID {
-			return errors.New("tile matrix ID should string representation of its index in the array: " + tm.ID)
-		}
-		if len(tm.VariableMatrixWidths) != 0 {
-			return errors.New("variable matrix widths are not supported: " + tm.ID)
-		}
 		if previousTM != nil {
 			if tmID != previousTMID+1 {
 				return errors.New("tile matrix IDs should be a range with step 1 starting with 0")
@@ -566,14 +666,24 @@ func IsQuadTree(tms tms20.TileMatrixSet) error {
 // if the res (span/size) is not "round" (with intgeom.Precision), a deviation will arise
 //
 //nolint:nakedret
-func DeviationStats(tms tms20.TileMatrixSet, deepestTMID tms20.TMID) (stats string, deviationInUnits, deviationInPixels float64, err error) {
-	bottomLeft, topRight, err := tms.MatrixBoundingBox(0)
-	if err != nil {
-		return
-	}
-	ix, err := FromTileMatrixSet(tms, deepestTMID)
-	if err != nil {
-		return
+func DeviationStats(tms tms20.TileMatrixSet, deepestTMID tms20.TMID, internalPixelResolution uint) (stats string, deviationInUnits, deviationInPixels float64, err error) {
+	var ix *PointIndex
+	var bottomLeft, topRight geom.Point
+	if IsQuadTree(tms) == nil {
+		if bottomLeft, topRight, err = tms.MatrixBoundingBox(0); err != nil {
+			return
+		}
+		if ix, err = FromTileMatrixSet(tms, deepestTMID, internalPixelResolution); err != nil {
+			return
+		}
+	} else {
+		if ix, err = FromTileMatrix(tms, deepestTMID, internalPixelResolution); err != nil {
+			return
+		}
+		// the (square) root extent, as it would be without ints
+		floatSpan := float64(ix.deepestSize) * tms.TileMatrices[deepestTMID].CellSize / float64(internalPixelResolution)
+		bottomLeft = geom.Point{intgeom.ToGeomOrd(ix.intExtent.MinX()), intgeom.ToGeomOrd(ix.intExtent.MinY())}
+		topRight = geom.Point{bottomLeft.X() + floatSpan, bottomLeft.Y() + floatSpan}
 	}
 	p := uint(intgeom.Precision + 1)
 	ps := strconv.Itoa(int(p))
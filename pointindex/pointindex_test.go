@@ -326,7 +326,7 @@ func TestPointIndex_InsertPoint_Deepest(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tms := loadEmbeddedTileMatrixSet(t, tt.tmsID)
-			ix, err := FromTileMatrixSet(tms, tt.tmID)
+			ix, err := FromTileMatrixSet(tms, tt.tmID, VectorTileInternalPixelResolution)
 			require.NoError(t, err)
 
 			err = ix.InsertPoint(tt.point)
@@ -484,6 +484,27 @@ func TestPointIndex_SnapClosestPoints(t *testing.T) {
 	}
 }
 
+func TestPointIndex_IntersectedPoints(t *testing.T) {
+	ix := newSimplePointIndex(4, 0.5)
+	require.NoError(t, ix.InsertPolygon(geom.Polygon{
+		{{0.0, 5.0}, {5.0, 4.0}, {5.0, 0.0}, {3.0, 0.0}, {0.0, 2.0}},
+		{{1.0, 3.0}, {3.0, 3.0}, {3.0, 1.0}, {1.25, 1.25}},
+	}))
+	line := geom.Line{{3.0, 0.0}, {0.0, 2.0}}
+	assert.Equal(t, [][2]float64{{3.25, 0.25}, {1.25, 1.25}, {0.25, 2.25}}, ix.IntersectedPoints(line, 4))
+	assert.Empty(t, ix.hitOnce, "hits should not be registered")
+	assert.Equal(t, 0.5, ix.Resolution(4))
+	assert.Equal(t, 2.0, ix.Resolution(2))
+}
+
+func TestPointIndex_Points(t *testing.T) {
+	ix := newSimplePointIndex(4, 0.5)
+	require.NoError(t, ix.InsertPolygon(geom.Polygon{{{5.0, 4.0}, {0.0, 5.0}, {5.0, 0.0}, {5.2, 0.3}}}))
+	assert.Equal(t, [][2]float64{{5.25, 0.25}, {5.25, 4.25}, {0.25, 5.25}}, ix.Points(4))
+	assert.Equal(t, [][2]float64{{5.0, 1.0}, {1.0, 5.0}, {5.0, 5.0}}, ix.Points(2))
+	assert.Empty(t, ix.Points(5))
+}
+
 func TestPointIndex_lineIntersects(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -542,12 +563,89 @@ func loadEmbeddedTileMatrixSet(t *testing.T, tmsID string) tms20.TileMatrixSet {
 	return tms
 }
 
+func loadTileMatrixSet(t *testing.T, idOrPath string) tms20.TileMatrixSet {
+	tms, err := tms20.LoadTileMatrixSet(idOrPath)
+	require.NoError(t, err)
+	return tms
+}
+
 func newPointIndexFromEmbeddedTileMatrixSet(t *testing.T, tmsID string, deepestTMID tms20.TMID) *PointIndex {
-	tms, err := FromTileMatrixSet(loadEmbeddedTileMatrixSet(t, tmsID), deepestTMID)
+	tms, err := FromTileMatrixSet(loadEmbeddedTileMatrixSet(t, tmsID), deepestTMID, VectorTileInternalPixelResolution)
 	require.Nil(t, err)
 	return tms
 }
 
+func TestFromTileMatrix(t *testing.T) {
+	tests := []struct {
+		name             string
+		tms              tms20.TileMatrixSet
+		tmID             tms20.TMID
+		resolution       uint
+		wantExtent       geom.Extent
+		wantDeepestLevel Level
+		wantWidth        uint
+		wantHeight       uint
+		inside           []geom.Point
+		outside          []geom.Point
+	}{
+		{
+			name:             "GNOSISGlobalGrid 2:1 matrix",
+			tms:              loadEmbeddedTileMatrixSet(t, "GNOSISGlobalGrid"),
+			tmID:             0,
+			resolution:       VectorTileInternalPixelResolution,
+			wantExtent:       geom.Extent{-180.0, -90.0, 180.0, 270.0},
+			wantDeepestLevel: 14,
+			wantWidth:        16384,
+			wantHeight:       8192,
+			inside:           []geom.Point{{-180.0, -90.0}, {179.99, 89.99}, {5.1, 52.3}},
+			outside:          []geom.Point{{0.0, 100.0}, {0.0, 269.0}, {180.0, 0.0}},
+		},
+		{
+			name:             "CDB1GlobalGrid negative id",
+			tms:              loadEmbeddedTileMatrixSet(t, "CDB1GlobalGrid"),
+			tmID:             -10,
+			resolution:       1,
+			wantExtent:       geom.Extent{-180.0, -90.0, 332.0, 422.0},
+			wantDeepestLevel: 9,
+			wantWidth:        360,
+			wantHeight:       180,
+			inside:           []geom.Point{{-180.0, -90.0}, {179.5, 89.5}},
+			outside:          []geom.Point{{180.5, 0.0}, {0.0, 90.5}},
+		},
+		{
+			name:             "CanadianNAD83_LCC not doubling",
+			tms:              loadEmbeddedTileMatrixSet(t, "CanadianNAD83_LCC"),
+			tmID:             1,
+			resolution:       VectorTileInternalPixelResolution,
+			wantExtent:       geom.Extent{-34655800.0, 39310000.0 - 32768*22489.6283125899/16, -34655800.0 + 32768*22489.6283125899/16, 39310000.0},
+			wantDeepestLevel: 15,
+			wantWidth:        32768,
+			wantHeight:       32768,
+			inside:           []geom.Point{{-2000000.0, 1000000.0}},
+			outside:          []geom.Point{{-34700000.0, 0.0}, {0.0, 39400000.0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix, err := FromTileMatrix(tt.tms, tt.tmID, tt.resolution)
+			require.NoError(t, err)
+			gotExtent := ix.intExtent.ToGeomExtent()
+			for i := range tt.wantExtent {
+				assert.InDelta(t, tt.wantExtent[i], gotExtent[i], 1e-3)
+			}
+			assert.Equal(t, tt.wantDeepestLevel, ix.DeepestLevel())
+			assert.Equal(t, tt.wantWidth, ix.deepestWidth)
+			assert.Equal(t, tt.wantHeight, ix.deepestHeight)
+			for _, point := range tt.inside {
+				assert.NoError(t, ix.InsertPoint(point), "inside %v", point)
+			}
+			for _, point := range tt.outside {
+				assert.ErrorAs(t, ix.InsertPoint(point), new(OutsideGridError), "outside %v", point)
+			}
+		})
+	}
+}
+
 func TestIsQuadTree(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -582,6 +680,14 @@ func TestIsQuadTree(t *testing.T) {
 			name:    "WorldMercatorWGS84Quad",
 			tms:     loadEmbeddedTileMatrixSet(t, "WorldMercatorWGS84Quad"),
 			wantErr: assertNoErr,
+		}, {
+			name:    "built",
+			tms:     newQuadTreeTileMatrixSet(t),
+			wantErr: assertNoErr,
+		}, {
+			name:    "WMTS with identifiers ending in the level",
+			tms:     loadTileMatrixSet(t, "../tms20/testdata/wmts_capabilities.xml#EPSG:28992"),
+			wantErr: assertNoErr,
 		},
 	}
 	for _, tt := range tests {
@@ -596,6 +702,7 @@ func TestDeviationStats(t *testing.T) {
 		name                  string
 		tms                   tms20.TileMatrixSet
 		deepestTMID           tms20.TMID
+		resolution            uint
 		wantStats             string
 		wantDeviationInUnits  float64
 		wantDeviationInPixels float64
@@ -606,6 +713,7 @@ func TestDeviationStats(t *testing.T) {
 			name:                  "NetherlandsRDNewQuad",
 			tms:                   loadEmbeddedTileMatrixSet(t, "NetherlandsRDNewQuad"),
 			deepestTMID:           16,
+			resolution:            VectorTileInternalPixelResolution,
 			wantDeviationInUnits:  0,
 			wantDeviationInPixels: 0,
 			margin:                1e-6, // micrometers
@@ -615,6 +723,7 @@ func TestDeviationStats(t *testing.T) {
 			name:                  "WebMercatorQuad",
 			tms:                   loadEmbeddedTileMatrixSet(t, "WebMercatorQuad"),
 			deepestTMID:           18,
+			resolution:            VectorTileInternalPixelResolution,
 			wantDeviationInUnits:  0,
 			wantDeviationInPixels: 0,
 			margin:                1,
@@ -624,6 +733,7 @@ func TestDeviationStats(t *testing.T) {
 			name:                  "WebMercatorQuad starting from 19 has more than 1 pixel deviation ... ;(",
 			tms:                   loadEmbeddedTileMatrixSet(t, "WebMercatorQuad"),
 			deepestTMID:           19,
+			resolution:            VectorTileInternalPixelResolution,
 			wantDeviationInUnits:  1,
 			wantDeviationInPixels: 6,
 			margin:                1,
@@ -633,15 +743,46 @@ func TestDeviationStats(t *testing.T) {
 			name:                  "EuropeanETRS89_LAEAQuad",
 			tms:                   loadEmbeddedTileMatrixSet(t, "EuropeanETRS89_LAEAQuad"),
 			deepestTMID:           15,
+			resolution:            VectorTileInternalPixelResolution,
 			wantDeviationInUnits:  0,
 			wantDeviationInPixels: 0,
 			margin:                1,
 			wantErr:               assertNoErr,
 		},
+		{
+			name:                  "NetherlandsRDNewQuad with an internal pixel resolution of 32 (extent 8192)",
+			tms:                   loadEmbeddedTileMatrixSet(t, "NetherlandsRDNewQuad"),
+			deepestTMID:           16,
+			resolution:            32,
+			wantDeviationInUnits:  0,
+			wantDeviationInPixels: 0,
+			margin:                1e-6, // micrometers
+			wantErr:               assertNoErr,
+		},
+		{
+			name:                  "WebMercatorQuad with an internal pixel resolution of 2 (extent 512) has less deviation on 19",
+			tms:                   loadEmbeddedTileMatrixSet(t, "WebMercatorQuad"),
+			deepestTMID:           19,
+			resolution:            2,
+			wantDeviationInUnits:  0,
+			wantDeviationInPixels: 0,
+			margin:                1,
+			wantErr:               assertNoErr,
+		},
+		{
+			name:                  "GNOSISGlobalGrid (not a quad tree) has deviation in degrees, but less than a pixel",
+			tms:                   loadEmbeddedTileMatrixSet(t, "GNOSISGlobalGrid"),
+			deepestTMID:           2,
+			resolution:            VectorTileInternalPixelResolution,
+			wantDeviationInUnits:  0,
+			wantDeviationInPixels: 0,
+			margin:                1e-3,
+			wantErr:               assertNoErr,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotStats, gotDeviationInUnits, gotDeviationInPixels, err := DeviationStats(tt.tms, tt.deepestTMID)
+			gotStats, gotDeviationInUnits, gotDeviationInPixels, err := DeviationStats(tt.tms, tt.deepestTMID, tt.resolution)
 			if !tt.wantErr(t, err, fmt.Sprintf("DeviationStats(%v, %v)", tt.tms.ID, tt.deepestTMID)) {
 				return
 			}
@@ -653,3 +794,17 @@ func TestDeviationStats(t *testing.T) {
 		})
 	}
 }
+
+func newQuadTreeTileMatrixSet(t *testing.T) tms20.TileMatrixSet {
+	t.Helper()
+	crs, err := tms20.ParseCRS("http://www.opengis.net/def/crs/EPSG/0/28992")
+	require.NoError(t, err)
+	tms, err := tms20.NewQuadTreeTileMatrixSet(tms20.QuadTreeOptions{
+		CRS:         crs,
+		BoundingBox: &geom.Extent{0, 300000, 280000, 620000},
+		TileSize:    256,
+		Levels:      15,
+	})
+	require.NoError(t, err)
+	return tms
+}
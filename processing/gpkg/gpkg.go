@@ -1,21 +1,28 @@
 package gpkg
 
 import (
+	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/geom/encoding/gpkg"
+	"github.com/pdok/texel/loghelp"
 	"github.com/pdok/texel/processing"
 )
 
 type featureGPKG struct {
+	fid      int64
 	columns  []interface{}
 	geometry geom.Geometry
 }
 
+func (f featureGPKG) FID() int64 {
+	return f.fid
+}
+
 func (f featureGPKG) Columns() []interface{} {
 	return f.columns
 }
@@ -41,6 +48,34 @@ type Table struct {
 	srs     gpkg.SpatialReferenceSystem
 }
 
+// SRS returns the authority and code of the table's spatial reference system, e.g. EPSG:28992
+func (t Table) SRS() string {
+	return fmt.Sprintf("%s:%d", strings.ToUpper(t.srs.Organization), t.srs.OrganizationCoordsysID)
+}
+
+// MayHavePolygons returns whether the table's geometry type allows (multi)polygons, the features that are snapped
+func (t Table) MayHavePolygons() bool {
+	switch t.gtype {
+	case gpkg.Polygon, gpkg.MultiPolygon, gpkg.Geometry:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithSRS returns a copy of the table in another spatial reference system, for writing transformed features
+func (t Table) WithSRS(name string, organization string, code int, definition string) Table {
+	t.srs = gpkg.SpatialReferenceSystem{
+		Name:                   name,
+		ID:                     code,
+		Organization:           organization,
+		OrganizationCoordsysID: code,
+		Definition:             definition,
+		Description:            name,
+	}
+	return t
+}
+
 // geometryTypeFromString returns the numeric value of a gometry string
 func geometryTypeFromString(geometrytype string) gpkg.GeometryType {
 	switch strings.ToUpper(geometrytype) {
@@ -83,13 +118,14 @@ func (source SourceGeopackage) ReadFeatures(features chan<- processing.Feature)
 
 	rows, err := source.handle.Query(source.Table.selectSQL())
 	if err != nil {
-		log.Fatalf("err during closing rows: %s", err)
+		loghelp.Fatal("error querying the source table", "table", source.Table.Name, "error", err)
 	}
 
 	cols, err := rows.Columns()
 	if err != nil {
-		log.Fatalf("error reading the columns: %s", err)
+		loghelp.Fatal("error reading the columns", "table", source.Table.Name, "error", err)
 	}
+	pkColumn := source.Table.pkColumn()
 
 	for rows.Next() {
 		vals := make([]interface{}, len(cols))
@@ -99,7 +135,7 @@ func (source SourceGeopackage) ReadFeatures(features chan<- processing.Feature)
 		}
 
 		if err = rows.Scan(valPtrs...); err != nil {
-			log.Fatalf("err reading row values: %v", err)
+			loghelp.Fatal("error reading row values", "table", source.Table.Name, "error", err)
 		}
 		var f featureGPKG
 		var c []interface{}
@@ -109,10 +145,15 @@ func (source SourceGeopackage) ReadFeatures(features chan<- processing.Feature)
 			case source.Table.gcolumn:
 				wkbgeom, err := gpkg.DecodeGeometry(vals[i].([]byte))
 				if err != nil {
-					log.Fatalf("error decoding the geometry: %s", err)
+					loghelp.Fatal("error decoding the geometry", "table", source.Table.Name, "error", err)
 				}
 				f.geometry = wkbgeom.Geometry
 			default:
+				if colName == pkColumn {
+					if fid, ok := vals[i].(int64); ok {
+						f.fid = fid
+					}
+				}
 				switch v := vals[i].(type) {
 				case []uint8:
 					asBytes := make([]byte, len(v))
@@ -129,7 +170,7 @@ func (source SourceGeopackage) ReadFeatures(features chan<- processing.Feature)
 				case nil:
 					c = append(c, v)
 				default:
-					log.Fatalf("unexpected type for sqlite column data: %v: %T", cols[i], v)
+					loghelp.Fatal("unexpected type for sqlite column data", "table", source.Table.Name, "column", cols[i], "type", fmt.Sprintf("%T", v))
 				}
 			}
 			f.columns = c
@@ -139,17 +180,29 @@ func (source SourceGeopackage) ReadFeatures(features chan<- processing.Feature)
 	}
 	err = rows.Err()
 	if err != nil {
-		log.Fatal(err)
+		loghelp.Fatal("error reading the source table", "table", source.Table.Name, "error", err)
 	}
 	close(features)
 	defer rows.Close()
 }
 
+// CountFeatures returns the number of features in the table, from gpkg_ogr_contents if it's there (as GDAL keeps it)
+// and otherwise by counting the rows
+func (source SourceGeopackage) CountFeatures() (int64, error) {
+	var count sql.NullInt64
+	err := source.handle.QueryRow(`SELECT feature_count FROM gpkg_ogr_contents WHERE table_name = ?;`, source.Table.Name).Scan(&count)
+	if err == nil && count.Valid {
+		return count.Int64, nil
+	}
+	err = source.handle.QueryRow(`SELECT COUNT(*) FROM "` + source.Table.Name + `";`).Scan(&count)
+	return count.Int64, err
+}
+
 func (source SourceGeopackage) GetTableInfo() []Table {
-	query := `SELECT table_name, column_name, geometry_type_name, srs_id FROM gpkg_geometry_columns;`
+	query := `SELECT table_name, column_name, geometry_type_name, srs_id FROM gpkg_geometry_columns ORDER BY table_name;`
 	rows, err := source.handle.Query(query)
 	if err != nil {
-		log.Fatalf("error during closing rows: %v - %v", query, err)
+		loghelp.Fatal("error querying the source table information", "query", query, "error", err)
 	}
 	var tables []Table
 
@@ -159,7 +212,7 @@ func (source SourceGeopackage) GetTableInfo() []Table {
 		var srsID int
 		err := rows.Scan(&t.Name, &t.gcolumn, &gtype, &srsID)
 		if err != nil {
-			log.Fatalf("error retrieving the source table information: %s", err)
+			loghelp.Fatal("error retrieving the source table information", "error", err)
 		}
 
 		t.columns = getTableColumns(source.handle, t.Name)
@@ -223,12 +276,12 @@ func (target *TargetGeopackage) WriteFeatures(inFeatures <-chan processing.Featu
 func (target *TargetGeopackage) writeFeatures(features []processing.Feature) {
 	tx, err := target.handle.Begin()
 	if err != nil {
-		log.Fatalf("Could not start a transaction: %s", err)
+		loghelp.Fatal("could not start a transaction", "table", target.Table.Name, "error", err)
 	}
 
 	stmt, err := tx.Prepare(target.Table.insertSQL())
 	if err != nil {
-		log.Fatalf("Could not prepare a statement: %s", err)
+		loghelp.Fatal("could not prepare a statement", "table", target.Table.Name, "error", err)
 	}
 
 	var ext *geom.Extent
@@ -236,7 +289,7 @@ func (target *TargetGeopackage) writeFeatures(features []processing.Feature) {
 	for _, f := range features {
 		sb, err := gpkg.NewBinary(int32(target.Table.srs.ID), f.Geometry())
 		if err != nil {
-			log.Fatalf("Could not create a binary geometry: %s", err)
+			loghelp.Fatal("could not create a binary geometry", "table", target.Table.Name, "fid", f.FID(), "error", err)
 		}
 
 		data := f.Columns()
@@ -248,14 +301,14 @@ func (target *TargetGeopackage) writeFeatures(features []processing.Feature) {
 			if len(data) > 0 {
 				fid = data[0]
 			}
-			log.Fatalf("Could not get a result summary from the prepared statement for fid %s: %s", fid, err)
+			loghelp.Fatal("could not get a result summary from the prepared statement", "table", target.Table.Name, "fid", fid, "error", err)
 		}
 
 		if ext == nil {
 			ext, err = geom.NewExtentFromGeometry(f.Geometry())
 			if err != nil {
 				ext = nil
-				log.Println("Failed to create new extent:", err)
+				slog.Warn("failed to create new extent", "table", target.Table.Name, "fid", f.FID(), "error", err)
 				continue
 			}
 		} else {
@@ -267,14 +320,14 @@ func (target *TargetGeopackage) writeFeatures(features []processing.Feature) {
 
 	err = target.handle.UpdateGeometryExtent(target.Table.Name, ext)
 	if err != nil {
-		log.Fatalln("Failed to update new extent:", err)
+		loghelp.Fatal("failed to update new extent", "table", target.Table.Name, "error", err)
 	}
 }
 
 func openGeopackage(file string) *gpkg.Handle {
 	handle, err := gpkg.Open(file)
 	if err != nil {
-		log.Fatalf("error opening GeoPackage: %s", err)
+		loghelp.Fatal("error opening GeoPackage", "file", file, "error", err)
 	}
 	return handle
 }
@@ -300,6 +353,16 @@ func (t Table) createSQL() string {
 	return query
 }
 
+// pkColumn returns the name of the primary key column, which holds the fid
+func (t Table) pkColumn() string {
+	for _, c := range t.columns {
+		if c.pk == 1 {
+			return c.name
+		}
+	}
+	return ""
+}
+
 // selectSQL build a SELECT statement based on the table and columns
 // used for reading the source features
 func (t Table) selectSQL() string {
@@ -307,8 +370,11 @@ func (t Table) selectSQL() string {
 	for _, c := range t.columns {
 		csql = append(csql, c.name)
 	}
-	query := `SELECT ` + strings.Join(csql, `,`) + ` FROM "` + t.Name + `";`
-	return query
+	query := `SELECT ` + strings.Join(csql, `,`) + ` FROM "` + t.Name + `"`
+	if pk := t.pkColumn(); pk != "" {
+		query += ` ORDER BY ` + pk // reproducible order of the features
+	}
+	return query + `;`
 }
 
 // insertSQL used for writing the features
@@ -349,14 +415,14 @@ func getTableColumns(h *gpkg.Handle, table string) []column {
 	rows, err := h.Query(fmt.Sprintf(query, table))
 
 	if err != nil {
-		log.Fatalf("err during closing rows: %v - %v", query, err)
+		loghelp.Fatal("error querying the table columns", "table", table, "error", err)
 	}
 
 	for rows.Next() {
 		var column column
 		err := rows.Scan(&column.cid, &column.name, &column.ctype, &column.notnull, &column.dfltValue, &column.pk)
 		if err != nil {
-			log.Fatalf("error getting the column information: %s", err)
+			loghelp.Fatal("error getting the column information", "table", table, "error", err)
 		}
 		columns = append(columns, column)
 	}
@@ -369,7 +435,7 @@ func buildTable(h *gpkg.Handle, t Table) error {
 	query := t.createSQL()
 	_, err := h.Exec(query)
 	if err != nil {
-		log.Fatalf("error building table in target GeoPackage: %s", err)
+		loghelp.Fatal("error building table in target GeoPackage", "table", t.Name, "error", err)
 	}
 
 	err = h.AddGeometryTable(gpkg.TableDescription{
@@ -384,7 +450,7 @@ func buildTable(h *gpkg.Handle, t Table) error {
 		M: gpkg.Prohibited,
 	})
 	if err != nil {
-		log.Println("error adding geometry table in target GeoPackage:", err)
+		slog.Error("error adding geometry table in target GeoPackage", "table", t.Name, "error", err)
 		return err
 	}
 	return nil
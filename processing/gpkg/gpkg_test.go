@@ -0,0 +1,69 @@
+package gpkg
+
+import (
+	"database/sql"
+	"path"
+	"testing"
+
+	"github.com/go-spatial/geom/encoding/gpkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceGeopackage_CountFeatures(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  []string
+		want int64
+	}{
+		{
+			name: "from gpkg_ogr_contents",
+			sql: []string{
+				`CREATE TABLE gpkg_ogr_contents (table_name TEXT NOT NULL PRIMARY KEY, feature_count INTEGER DEFAULT NULL);`,
+				`INSERT INTO gpkg_ogr_contents VALUES ('features', 42);`,
+			},
+			want: 42,
+		},
+		{
+			name: "without gpkg_ogr_contents",
+			want: 3,
+		},
+		{
+			name: "not in gpkg_ogr_contents",
+			sql: []string{
+				`CREATE TABLE gpkg_ogr_contents (table_name TEXT NOT NULL PRIMARY KEY, feature_count INTEGER DEFAULT NULL);`,
+				`INSERT INTO gpkg_ogr_contents VALUES ('other', 42);`,
+			},
+			want: 3,
+		},
+		{
+			name: "unknown count in gpkg_ogr_contents",
+			sql: []string{
+				`CREATE TABLE gpkg_ogr_contents (table_name TEXT NOT NULL PRIMARY KEY, feature_count INTEGER DEFAULT NULL);`,
+				`INSERT INTO gpkg_ogr_contents VALUES ('features', NULL);`,
+			},
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// plain SQLite (without spatialite) is enough for counting
+			db, err := sql.Open("sqlite3", path.Join(t.TempDir(), "source.gpkg"))
+			require.NoError(t, err)
+			defer db.Close()
+			statements := append([]string{
+				`CREATE TABLE features (fid INTEGER PRIMARY KEY, name TEXT);`,
+				`INSERT INTO features (name) VALUES ('a'), ('b'), ('c');`,
+			}, tt.sql...)
+			for _, statement := range statements {
+				_, err := db.Exec(statement)
+				require.NoError(t, err)
+			}
+			source := SourceGeopackage{Table: Table{Name: "features"}, handle: &gpkg.Handle{DB: db}}
+
+			got, err := source.CountFeatures()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -5,12 +5,15 @@ import (
 )
 
 type Feature interface {
+	// FID is the feature's identifier (primary key) in the Source
+	FID() int64
 	Columns() []interface{}
 	Geometry() geom.Geometry
 }
 
 type FeatureForTileMatrix interface {
 	Feature
+	TileMatrixSetID() string
 	TileMatrixID() int
 }
 
@@ -3,11 +3,16 @@
 package processing
 
 import (
+	"cmp"
 	"fmt"
-	"log"
+	"runtime/debug"
+	"slices"
 	"sync"
 
+	"github.com/pdok/texel/loghelp"
+	"github.com/pdok/texel/mapslicehelp"
 	"github.com/pdok/texel/tms20"
+	"golang.org/x/exp/maps"
 
 	"github.com/go-spatial/geom"
 )
@@ -18,74 +23,140 @@ func readFeaturesFromSource(source Source, features chan<- Feature) {
 	source.ReadFeatures(features)
 }
 
-// processFeatures processes the geometries in the features with the given function
-func processFeatures(featuresIn <-chan Feature, featuresOut chan<- FeatureForTileMatrix, tmIDs []tms20.TMID, f processPolygonFunc) {
+// Counts are the figures of processing the features of a source
+type Counts struct {
+	FeaturesIn    uint64
+	NonPolygons   uint64
+	MultiPolygons uint64
+	// FeaturesKept are the features kept for any of the targets
+	FeaturesKept uint64
+	// FeaturesOut are the features written per target
+	FeaturesOut map[TargetKey]uint64
+}
+
+// processFeatures processes the geometries in the features with the given functions, per tile matrix set
+func processFeatures(featuresIn <-chan Feature, featuresOut chan<- FeatureForTileMatrix, tileMatrixSets []TileMatrixSetProcessing, counts *Counts) {
 	var preCount, postCount, nonPolygonCount, multiPolygonCount uint64
+	counts.FeaturesOut = make(map[TargetKey]uint64)
+	tileMatrixIDs := make([][]tms20.TMID, len(tileMatrixSets))
+	for i, tileMatrixSet := range tileMatrixSets {
+		tileMatrixIDs[i] = tileMatrixSet.tileMatrixIDs()
+	}
 	for {
 		feature, hasMore := <-featuresIn
 		if !hasMore {
 			break
 		}
 		preCount++
+		kept := false
+		for i, tileMatrixSet := range tileMatrixSets {
+			if processFeatureForTileMatrixSet(feature, tileMatrixSet, tileMatrixIDs[i], featuresOut, counts.FeaturesOut) {
+				kept = true
+			}
+		}
+		if kept {
+			postCount++
+		}
 		switch feature.Geometry().(type) {
 		case geom.Polygon:
-			polygon := feature.Geometry().(geom.Polygon)
-			newPolygonsPerTileMatrix := f(polygon, tmIDs)
-			if len(newPolygonsPerTileMatrix) > 0 {
-				postCount++
-			}
-			for tmID, newPolygons := range newPolygonsPerTileMatrix {
-				var newGeometry geom.Geometry
-				if len(newPolygons) == 0 { // should never happen
-					panic(fmt.Errorf("no new polygon for level %v", tmID))
-				}
-				if len(newPolygons) == 1 {
-					newGeometry = newPolygons[0]
-				} else {
-					// TODO polygons are combined into multipolygons, for now here
-					// later, processPolygonFunc could return abstract geometry(s) if also lines/points are returned
-					newGeometry = polygonsToMulti(newPolygons)
-				}
-				featuresOut <- wrapFeatureForTileMatrix(feature, tmID, newGeometry)
-			}
 		case geom.MultiPolygon:
-			multiPolygon := feature.Geometry().(geom.MultiPolygon)
-			newMultiPolygonPerTileMatrix := processMultiPolygon(multiPolygon, tmIDs, f)
-			if len(newMultiPolygonPerTileMatrix) > 0 {
-				postCount++
-			}
-			for tmID, newMultiPolygon := range newMultiPolygonPerTileMatrix {
-				featuresOut <- wrapFeatureForTileMatrix(feature, tmID, newMultiPolygon)
-			}
+			multiPolygonCount++
 		default:
-			postCount++
 			nonPolygonCount++
-			for _, tmID := range tmIDs {
-				featuresOut <- wrapFeatureForTileMatrix(feature, tmID, nil)
-			}
 		}
 	}
+	*counts = Counts{
+		FeaturesIn:    preCount,
+		NonPolygons:   nonPolygonCount,
+		MultiPolygons: multiPolygonCount,
+		FeaturesKept:  postCount,
+		FeaturesOut:   counts.FeaturesOut,
+	}
 	close(featuresOut)
+}
 
-	log.Printf("    total features: %d", preCount)
-	log.Printf("      non-polygons: %d", nonPolygonCount)
-	if preCount != nonPolygonCount {
-		log.Printf("     multipolygons: %d", multiPolygonCount)
+// processFeatureForTileMatrixSet processes (and transforms) the geometry of a feature for the tile matrices in a tile matrix set.
+// Returns whether the feature is kept (for any tile matrix). The features sent out are counted per target.
+func processFeatureForTileMatrixSet(feature Feature, tileMatrixSet TileMatrixSetProcessing, tmIDs []tms20.TMID,
+	featuresOut chan<- FeatureForTileMatrix, featuresOutCounts map[TargetKey]uint64) bool {
+	geometry := feature.Geometry()
+	if tileMatrixSet.Transform != nil {
+		var err error
+		if geometry, err = tileMatrixSet.Transform(geometry); err != nil {
+			loghelp.Fatal("error transforming feature", "fid", feature.FID(), "error", err)
+		}
+	}
+	f := tileMatrixSet.recoveringF()
+	send := func(tmID tms20.TMID, newGeometry geom.Geometry) {
+		key := TargetKey{TileMatrixSetID: tileMatrixSet.ID, TileMatrixID: tmID}
+		featuresOutCounts[key]++
+		featuresOut <- wrapFeatureForTileMatrix(feature, key, newGeometry)
+	}
+	validate := func(newPolygonsPerTileMatrix map[tms20.TMID][]geom.Polygon) map[tms20.TMID][]geom.Polygon {
+		if tileMatrixSet.Validate == nil {
+			return newPolygonsPerTileMatrix
+		}
+		validPolygonsPerTileMatrix, err := tileMatrixSet.Validate(feature.FID(), geometry, newPolygonsPerTileMatrix)
+		if err != nil {
+			loghelp.Fatal("error validating feature", "fid", feature.FID(), "error", err)
+		}
+		return validPolygonsPerTileMatrix
+	}
+	switch geometry := geometry.(type) {
+	case geom.Polygon:
+		newPolygonsPerTileMatrix, err := f(feature.FID(), geometry, tmIDs)
+		if err != nil {
+			loghelp.Fatal("error processing feature", "fid", feature.FID(), "error", err)
+		}
+		newPolygonsPerTileMatrix = validate(newPolygonsPerTileMatrix)
+		for _, tmID := range mapslicehelp.SortedKeys(newPolygonsPerTileMatrix) {
+			newPolygons := newPolygonsPerTileMatrix[tmID]
+			var newGeometry geom.Geometry
+			if len(newPolygons) == 0 { // should never happen
+				panic(fmt.Errorf("no new polygon for level %v", tmID))
+			}
+			if len(newPolygons) == 1 {
+				newGeometry = newPolygons[0]
+			} else {
+				// TODO polygons are combined into multipolygons, for now here
+				// later, processPolygonFunc could return abstract geometry(s) if also lines/points are returned
+				newGeometry = polygonsToMulti(newPolygons)
+			}
+			send(tmID, newGeometry)
+		}
+		return len(newPolygonsPerTileMatrix) > 0
+	case geom.MultiPolygon:
+		newPolygonsPerTileMatrix, err := processMultiPolygon(feature.FID(), geometry, tmIDs, f)
+		if err != nil {
+			loghelp.Fatal("error processing feature", "fid", feature.FID(), "error", err)
+		}
+		newPolygonsPerTileMatrix = validate(newPolygonsPerTileMatrix)
+		for _, tmID := range mapslicehelp.SortedKeys(newPolygonsPerTileMatrix) {
+			send(tmID, polygonsToMulti(newPolygonsPerTileMatrix[tmID]))
+		}
+		return len(newPolygonsPerTileMatrix) > 0
+	default:
+		for _, tmID := range tmIDs {
+			send(tmID, geometry)
+		}
+		return true
 	}
-	log.Printf("              kept: %d", postCount)
 }
 
 // writeFeatures collects the processed features by the processFeatures and
 // creates a WKB binary from the geometry
 // The collected feature array, based on the pagesize, is then passed to the writeFeaturesArray
-func writeFeaturesToTargets(featuresForTileMatrices <-chan FeatureForTileMatrix, targets map[int]Target) {
-	targetChannels := make(map[int]chan<- Feature)
+func writeFeaturesToTargets(featuresForTileMatrices <-chan FeatureForTileMatrix, targets map[TargetKey]Target) {
+	targetChannels := make(map[TargetKey]chan<- Feature)
 	wg := sync.WaitGroup{}
 
-	// create a channel and start a goroutine per tile matrix target
-	for tmID, target := range targets {
+	// create a channel and start a goroutine per tile matrix target (in order, for reproducible runs)
+	keys := maps.Keys(targets)
+	slices.SortFunc(keys, TargetKey.compare)
+	for _, key := range keys {
+		target := targets[key]
 		targetChannel := make(chan Feature)
-		targetChannels[tmID] = targetChannel
+		targetChannels[key] = targetChannel
 		wg.Add(1)
 		go func(target Target) {
 			defer wg.Done()
@@ -99,64 +170,155 @@ func writeFeaturesToTargets(featuresForTileMatrices <-chan FeatureForTileMatrix,
 		if !ok {
 			break
 		}
-		tmID := feature.TileMatrixID()
-		channel := targetChannels[tmID]
+		key := TargetKey{TileMatrixSetID: feature.TileMatrixSetID(), TileMatrixID: feature.TileMatrixID()}
+		channel := targetChannels[key]
 		if channel == nil { // should never happen
-			panic(fmt.Errorf(`no target channel for %v`, tmID))
+			panic(fmt.Errorf(`no target channel for %v`, key))
 		}
 		channel <- feature
 	}
 
 	// close the channels, the targets will do their last writing
-	for _, targetChannel := range targetChannels {
-		close(targetChannel)
+	for _, key := range keys {
+		close(targetChannels[key])
 	}
 
 	wg.Wait()
 }
 
-// processMultiPolygon will split itself into the separated polygons that will be processed before building a new MULTIPOLYGON
-func processMultiPolygon(multiPolygon geom.MultiPolygon, tileMatrixIDs []tms20.TMID, f processPolygonFunc) map[tms20.TMID]geom.MultiPolygon {
-	newMultiPolygonPerTileMatrix := make(map[tms20.TMID]geom.MultiPolygon, len(tileMatrixIDs))
+// processMultiPolygon will split itself into the separated polygons that will be processed,
+// collecting the polygons for a new MULTIPOLYGON per tile matrix
+func processMultiPolygon(fid int64, multiPolygon geom.MultiPolygon, tileMatrixIDs []tms20.TMID, f processPolygonFunc) (map[tms20.TMID][]geom.Polygon, error) {
+	newPolygonsPerTileMatrix := make(map[tms20.TMID][]geom.Polygon, len(tileMatrixIDs))
 	for _, polygon := range multiPolygon {
-		newPolygonsPerTileMatrix := f(polygon, tileMatrixIDs)
-		for tmID, newPolygons := range newPolygonsPerTileMatrix {
-			for _, newPolygon := range newPolygons {
-				// if the processing results in multiple polygons, they are just added to the single resulting multipoly
-				newMultiPolygonPerTileMatrix[tmID] = append(newMultiPolygonPerTileMatrix[tmID], newPolygon)
-			}
+		newPolygonsPerTileMatrixForPolygon, err := f(fid, polygon, tileMatrixIDs)
+		if err != nil {
+			return nil, err
+		}
+		for tmID, newPolygons := range newPolygonsPerTileMatrixForPolygon {
+			// if the processing results in multiple polygons, they are just added to the single resulting multipoly
+			newPolygonsPerTileMatrix[tmID] = append(newPolygonsPerTileMatrix[tmID], newPolygons...)
 		}
 	}
-	return newMultiPolygonPerTileMatrix
+	return newPolygonsPerTileMatrix, nil
 }
 
-type processPolygonFunc func(p geom.Polygon, tileMatrixIDs []tms20.TMID) map[tms20.TMID][]geom.Polygon
+type processPolygonFunc func(fid int64, p geom.Polygon, tileMatrixIDs []tms20.TMID) (map[tms20.TMID][]geom.Polygon, error)
+
+// validateFunc validates the processed polygons of a whole feature (the polygon or multipolygon geometry),
+// returning the polygons to keep per tile matrix
+type validateFunc func(fid int64, geometry geom.Geometry, polygonsPerTileMatrix map[tms20.TMID][]geom.Polygon) (map[tms20.TMID][]geom.Polygon, error)
+
+// TargetKey identifies the target for a tile matrix of a tile matrix set
+type TargetKey struct {
+	TileMatrixSetID string
+	TileMatrixID    tms20.TMID
+}
+
+func (k TargetKey) String() string {
+	return fmt.Sprintf("%s/%d", k.TileMatrixSetID, k.TileMatrixID)
+}
+
+func (k TargetKey) compare(other TargetKey) int {
+	if c := cmp.Compare(k.TileMatrixSetID, other.TileMatrixSetID); c != 0 {
+		return c
+	}
+	return cmp.Compare(k.TileMatrixID, other.TileMatrixID)
+}
+
+// TileMatrixSetProcessing is how the features are processed for (the tile matrices of) one tile matrix set
+type TileMatrixSetProcessing struct {
+	// ID of the tile matrix set, unique within a run
+	ID string
+	// Targets per tile matrix
+	Targets map[tms20.TMID]Target
+	// Transform is applied before F and to the non-polygons. Optional, e.g. reprojecting to the CRS of the tile matrix set
+	Transform transformFunc
+	F         processPolygonFunc
+	// Validate is applied to the result of F for a whole feature, so all polygons of a multipolygon together.
+	// Optional, e.g. to leave out the invalid results.
+	Validate validateFunc
+	// OnPanic is called when F panics, e.g. to write a reproduction of the panic.
+	// Returns the error the processing fails with. Optional, by default the PanicError itself.
+	OnPanic func(PanicError) error
+}
+
+// PanicError is a recovered panic of processing (a polygon of) a feature
+type PanicError struct {
+	FID           int64
+	Polygon       geom.Polygon
+	TileMatrixIDs []tms20.TMID
+	Recovered     any
+	Stack         []byte
+}
+
+func (e PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Recovered)
+}
+
+// recoveringF returns F, recovering from a panic as an error
+func (p TileMatrixSetProcessing) recoveringF() processPolygonFunc {
+	return func(fid int64, polygon geom.Polygon, tmIDs []tms20.TMID) (newPolygons map[tms20.TMID][]geom.Polygon, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr := PanicError{FID: fid, Polygon: polygon, TileMatrixIDs: tmIDs, Recovered: r, Stack: debug.Stack()}
+				err = panicErr
+				if p.OnPanic != nil {
+					err = p.OnPanic(panicErr)
+				}
+			}
+		}()
+		return p.F(fid, polygon, tmIDs)
+	}
+}
+
+// tileMatrixIDs returns the (sorted) IDs of the tile matrices with a target
+func (p TileMatrixSetProcessing) tileMatrixIDs() []tms20.TMID {
+	return mapslicehelp.SortedKeys(p.Targets)
+}
 
 // ProcessFeatures applies the processing function/operation to each Target.
-func ProcessFeatures(source Source, targets map[tms20.TMID]Target, f processPolygonFunc) {
+func ProcessFeatures(source Source, targets map[tms20.TMID]Target, f processPolygonFunc) Counts {
+	return ProcessFeaturesForTileMatrixSets(source, []TileMatrixSetProcessing{{Targets: targets, F: f}})
+}
+
+// ProcessFeaturesForTileMatrixSets reads the source once and applies the processing per tile matrix set
+// to each of its Targets. Returns the counts of the features processed.
+func ProcessFeaturesForTileMatrixSets(source Source, tileMatrixSets []TileMatrixSetProcessing) Counts {
 	featuresBefore := make(chan Feature)
 	featuresAfter := make(chan FeatureForTileMatrix)
-	tileMatrixIDs := make([]tms20.TMID, 0, len(targets))
-	for tmID := range targets {
-		tileMatrixIDs = append(tileMatrixIDs, tmID)
+	targets := make(map[TargetKey]Target)
+	for _, tileMatrixSet := range tileMatrixSets {
+		for tmID, target := range tileMatrixSet.Targets {
+			targets[TargetKey{TileMatrixSetID: tileMatrixSet.ID, TileMatrixID: tmID}] = target
+		}
 	}
 
+	var counts Counts
 	wg := sync.WaitGroup{}
-	wg.Add(1)
+	wg.Add(2)
 	go func() {
 		defer wg.Done()
 		writeFeaturesToTargets(featuresAfter, targets)
 	}()
-	go processFeatures(featuresBefore, featuresAfter, tileMatrixIDs, f)
+	go func() {
+		defer wg.Done()
+		processFeatures(featuresBefore, featuresAfter, tileMatrixSets, &counts)
+	}()
 	go readFeaturesFromSource(source, featuresBefore)
 
 	wg.Wait()
+	return counts
 }
 
 type featureForTileMatrixWrapper struct {
-	wrapped      Feature
-	newGeometry  geom.Geometry
-	tileMatrixID int
+	wrapped     Feature
+	newGeometry geom.Geometry
+	targetKey   TargetKey
+}
+
+func (f *featureForTileMatrixWrapper) FID() int64 {
+	return f.wrapped.FID()
 }
 
 func (f *featureForTileMatrixWrapper) Columns() []interface{} {
@@ -170,15 +332,19 @@ func (f *featureForTileMatrixWrapper) Geometry() geom.Geometry {
 	return f.newGeometry
 }
 
+func (f *featureForTileMatrixWrapper) TileMatrixSetID() string {
+	return f.targetKey.TileMatrixSetID
+}
+
 func (f *featureForTileMatrixWrapper) TileMatrixID() int {
-	return f.tileMatrixID
+	return f.targetKey.TileMatrixID
 }
 
-func wrapFeatureForTileMatrix(feature Feature, tileMatrixID int, newGeometry geom.Geometry) FeatureForTileMatrix {
+func wrapFeatureForTileMatrix(feature Feature, targetKey TargetKey, newGeometry geom.Geometry) FeatureForTileMatrix {
 	return &featureForTileMatrixWrapper{
-		wrapped:      feature,
-		newGeometry:  newGeometry,
-		tileMatrixID: tileMatrixID,
+		wrapped:     feature,
+		newGeometry: newGeometry,
+		targetKey:   targetKey,
 	}
 }
 
@@ -0,0 +1,72 @@
+package processing
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-spatial/geom"
+)
+
+// SegmentLengthHistogram counts the lengths of the segments in the (MULTI)POLYGONs of sources,
+// in buckets of powers of two. Which is precise enough to compare with the cell sizes of a quad tree.
+type SegmentLengthHistogram struct {
+	counts map[int]uint64 // by floor(log2(length))
+	total  uint64
+}
+
+// Add reads all features from a source and counts the lengths of the segments of the (MULTI)POLYGONs
+func (h *SegmentLengthHistogram) Add(source Source) {
+	if h.counts == nil {
+		h.counts = make(map[int]uint64)
+	}
+	features := make(chan Feature)
+	go readFeaturesFromSource(source, features)
+	for feature := range features {
+		switch geometry := feature.Geometry().(type) {
+		case geom.Polygon:
+			h.addPolygon(geometry)
+		case geom.MultiPolygon:
+			for _, polygon := range geometry {
+				h.addPolygon(polygon)
+			}
+		}
+	}
+}
+
+func (h *SegmentLengthHistogram) addPolygon(polygon geom.Polygon) {
+	for _, ring := range polygon {
+		for i := range ring {
+			a, b := ring[i], ring[(i+1)%len(ring)]
+			length := math.Hypot(b[0]-a[0], b[1]-a[1])
+			if length == 0 {
+				continue
+			}
+			h.counts[int(math.Floor(math.Log2(length)))]++
+			h.total++
+		}
+	}
+}
+
+// Percentile returns the length below which (about) the fraction of the segments is,
+// interpolated (on a log scale) within the power of two bucket it falls in. Returns 0 if no segments were counted.
+func (h *SegmentLengthHistogram) Percentile(fraction float64) float64 {
+	if h.total == 0 {
+		return 0
+	}
+	buckets := make([]int, 0, len(h.counts))
+	for bucket := range h.counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+	wanted := fraction * float64(h.total)
+	var cumulative uint64
+	for _, bucket := range buckets {
+		count := h.counts[bucket]
+		if float64(cumulative+count) >= wanted {
+			within := max(wanted-float64(cumulative), 0) / float64(count)
+			return math.Pow(2, float64(bucket)+within)
+		}
+		cumulative += count
+	}
+	return math.Pow(2, float64(buckets[len(buckets)-1]+1))
+}
@@ -0,0 +1,32 @@
+package processing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentLengthHistogram_Percentile(t *testing.T) {
+	var h SegmentLengthHistogram
+	assert.Equal(t, 0.0, h.Percentile(0.5))
+
+	h.counts = make(map[int]uint64)
+	h.addPolygon(geom.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}) // 4 segments in [1, 2)
+	h.addPolygon(geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}) // 4 segments in [4, 8)
+	tests := []struct {
+		fraction float64
+		want     float64
+	}{
+		{fraction: 0, want: 1},
+		{fraction: 0.25, want: math.Sqrt2},
+		{fraction: 0.5, want: 2},
+		{fraction: 0.75, want: 4 * math.Sqrt2},
+		{fraction: 1, want: 8},
+		{fraction: 1.5, want: 8},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, h.Percentile(tt.fraction), 1e-9, tt.fraction)
+	}
+}
@@ -0,0 +1,50 @@
+package processing
+
+import (
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/loghelp"
+)
+
+// transformFunc transforms (e.g. reprojects) a geometry
+type transformFunc func(geom.Geometry) (geom.Geometry, error)
+
+// TransformSource wraps a source, so that the geometries of its features are transformed (e.g. reprojected)
+// before they are processed
+func TransformSource(source Source, transform transformFunc) Source {
+	return transformingSource{source: source, transform: transform}
+}
+
+type transformingSource struct {
+	source    Source
+	transform transformFunc
+}
+
+func (s transformingSource) ReadFeatures(features chan<- Feature) {
+	untransformed := make(chan Feature)
+	go readFeaturesFromSource(s.source, untransformed)
+	for feature := range untransformed {
+		geometry, err := s.transform(feature.Geometry())
+		if err != nil {
+			loghelp.Fatal("error transforming feature", "fid", feature.FID(), "error", err)
+		}
+		features <- transformedFeature{wrapped: feature, geometry: geometry}
+	}
+	close(features)
+}
+
+type transformedFeature struct {
+	wrapped  Feature
+	geometry geom.Geometry
+}
+
+func (f transformedFeature) FID() int64 {
+	return f.wrapped.FID()
+}
+
+func (f transformedFeature) Columns() []interface{} {
+	return f.wrapped.Columns()
+}
+
+func (f transformedFeature) Geometry() geom.Geometry {
+	return f.geometry
+}
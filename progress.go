@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pdok/texel/processing"
+)
+
+type progressMode string
+
+const (
+	progressAuto        progressMode = "auto"
+	progressInteractive progressMode = "interactive"
+	progressLog         progressMode = "log"
+	progressOff         progressMode = "off"
+)
+
+// interactiveRefresh is how often the progress line is redrawn in interactive mode
+const interactiveRefresh = time.Second
+
+// parseProgressMode parses the mode, with auto being interactive on a terminal and log lines otherwise (e.g. in a container)
+func parseProgressMode(s string) (progressMode, error) {
+	switch m := progressMode(s); m {
+	case progressAuto:
+		if stderr, err := os.Stderr.Stat(); err == nil && stderr.Mode()&os.ModeCharDevice != 0 {
+			return progressInteractive, nil
+		}
+		return progressLog, nil
+	case progressInteractive, progressLog, progressOff:
+		return m, nil
+	default:
+		return progressOff, fmt.Errorf(`unknown progress mode "%s", should be "%s", "%s", "%s" or "%s"`,
+			s, progressAuto, progressInteractive, progressLog, progressOff)
+	}
+}
+
+// progress reports the progress of snapping a table periodically: the features processed, the rate, the ETA and the memory use.
+// A nil progress reports nothing.
+type progress struct {
+	mode      progressMode
+	table     string
+	total     int64 // 0 if unknown
+	started   time.Time
+	processed atomic.Int64
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// startProgress starts reporting the progress of snapping a table with total features, every (positive) interval in log mode
+func startProgress(mode progressMode, interval time.Duration, table string, total int64) *progress {
+	if mode == progressOff {
+		return nil
+	}
+	if mode == progressInteractive {
+		interval = interactiveRefresh
+	}
+	p := &progress{mode: mode, table: table, total: total, started: time.Now(), done: make(chan struct{})}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.report()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// countSource wraps the source, so that the features read from it are counted as processed
+func (p *progress) countSource(source processing.Source) processing.Source {
+	if p == nil {
+		return source
+	}
+	return countingSource{source: source, count: &p.processed}
+}
+
+// finish stops reporting, after a last report
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	close(p.done)
+	p.wg.Wait()
+	p.report()
+	if p.mode == progressInteractive {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) report() {
+	processed := p.processed.Load()
+	rate, percent, eta := estimate(processed, p.total, time.Since(p.started))
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	heapMiB := memStats.HeapAlloc / (1 << 20)
+
+	switch p.mode {
+	case progressInteractive:
+		line := fmt.Sprintf("snapping %s: %d features, %.0f/s, %d MiB", p.table, processed, rate, heapMiB)
+		if p.total > 0 {
+			line = fmt.Sprintf("snapping %s: %d/%d features (%.1f%%), %.0f/s, ETA %s, %d MiB",
+				p.table, processed, p.total, percent, rate, eta, heapMiB)
+		}
+		fmt.Fprint(os.Stderr, "\r\033[K"+line) // overwrite the line
+	case progressLog:
+		args := []any{"stage", "snapping", "table", p.table, "processed", processed, "featuresPerSecond", int64(rate), "heapMiB", heapMiB}
+		if p.total > 0 {
+			args = append(args, "total", p.total, "percent", int(percent), "eta", eta.String())
+		}
+		slog.Info("progress", args...)
+	}
+}
+
+// estimate returns the rate (features per second) after processing some features in the elapsed time
+// and, if the total is known, the percentage processed and the time left at that rate (rounded to seconds)
+func estimate(processed int64, total int64, elapsed time.Duration) (rate float64, percent float64, eta time.Duration) {
+	if elapsed > 0 {
+		rate = float64(processed) / elapsed.Seconds()
+	}
+	if total > 0 {
+		percent = 100 * float64(processed) / float64(total)
+		if rate > 0 {
+			eta = time.Duration(float64(max(total-processed, 0)) / rate * float64(time.Second)).Round(time.Second)
+		}
+	}
+	return rate, percent, eta
+}
+
+// countingSource counts the features read from the source, as they are passed on to be processed
+type countingSource struct {
+	source processing.Source
+	count  *atomic.Int64
+}
+
+func (s countingSource) ReadFeatures(features chan<- processing.Feature) {
+	read := make(chan processing.Feature)
+	go s.source.ReadFeatures(read)
+	for feature := range read {
+		features <- feature
+		s.count.Add(1)
+	}
+	close(features)
+}
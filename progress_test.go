@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress_estimate(t *testing.T) {
+	tests := []struct {
+		name        string
+		processed   int64
+		total       int64
+		elapsed     time.Duration
+		wantRate    float64
+		wantPercent float64
+		wantETA     time.Duration
+	}{
+		{name: "a quarter", processed: 250, total: 1000, elapsed: 10 * time.Second, wantRate: 25, wantPercent: 25, wantETA: 30 * time.Second},
+		{name: "done", processed: 1000, total: 1000, elapsed: 40 * time.Second, wantRate: 25, wantPercent: 100, wantETA: 0},
+		{name: "more than counted", processed: 1200, total: 1000, elapsed: 40 * time.Second, wantRate: 30, wantPercent: 120, wantETA: 0},
+		{name: "eta rounded to seconds", processed: 3, total: 10, elapsed: 2 * time.Second, wantRate: 1.5, wantPercent: 30, wantETA: 5 * time.Second},
+		{name: "unknown total", processed: 500, total: 0, elapsed: 5 * time.Second, wantRate: 100, wantPercent: 0, wantETA: 0},
+		{name: "nothing processed yet", processed: 0, total: 1000, elapsed: 5 * time.Second, wantRate: 0, wantPercent: 0, wantETA: 0},
+		{name: "no time elapsed yet", processed: 0, total: 1000, elapsed: 0, wantRate: 0, wantPercent: 0, wantETA: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, percent, eta := estimate(tt.processed, tt.total, tt.elapsed)
+			assert.InDelta(t, tt.wantRate, rate, 1e-9)
+			assert.InDelta(t, tt.wantPercent, percent, 1e-9)
+			assert.Equal(t, tt.wantETA, eta)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/pointindex"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/processing/gpkg"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/svg"
+	"github.com/pdok/texel/tms20"
+	"github.com/urfave/cli/v2"
+)
+
+const GRIDTILEMATRIX string = `gridtilematrix`
+const TABLE string = `table`
+const FID string = `fid`
+const WIDTH string = `width`
+
+//nolint:funlen
+func svgCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "svg",
+		Usage:     "Draw a (MULTI)POLYGON before and after snapping as SVG, with the pixel grid and the points snapped to",
+		ArgsUsage: "[geometry as WKT or GeoJSON, read from stdin if left out and no source GPKG is given]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     TILEMATRIXSET,
+				Aliases:  []string{"tms"},
+				Usage:    `ID of a (built-in) tile matrix set or a tile matrix set JSON file. E.g.: NetherlandsRDNewQuad`,
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     TILEMATRICES,
+				Aliases:  []string{"z"},
+				Usage:    `IDs of the tile matrices to snap for, a layer each. JSON array of integers. E.g.: [5,6,7]`,
+				Required: true,
+			},
+			&cli.IntFlag{
+				Name:    GRIDTILEMATRIX,
+				Aliases: []string{"gtm"},
+				Usage:   "ID of the tile matrix to draw the (tile) pixel grid and the points (of the internal pixel grid) for. Defaults to the deepest one snapped for",
+			},
+			&cli.StringFlag{
+				Name:    SOURCE,
+				Aliases: []string{"s"},
+				Usage:   "Source GPKG to read the features from, instead of a geometry. In the CRS of the tile matrix set",
+			},
+			&cli.StringFlag{
+				Name:  TABLE,
+				Usage: "Table in the source GPKG",
+			},
+			&cli.Int64Flag{
+				Name:  FID,
+				Usage: "Feature in the source GPKG table to draw",
+			},
+			&cli.StringFlag{
+				Name:  BBOX,
+				Usage: `Extent to draw, in XY order. JSON array of minx, miny, maxx, maxy. Without a fid all features of the source GPKG table in it are drawn. Defaults to the extent of the geometries`,
+			},
+			&cli.BoolFlag{
+				Name:    KEEPPOINTSANDLINES,
+				Aliases: []string{"k"},
+				Usage:   "to keep points and lines in the output",
+			},
+			&cli.UintFlag{
+				Name:    INTERNALPIXELRESOLUTION,
+				Aliases: []string{"ipr"},
+				Usage:   "Number of internal pixels (on one axis) a tile pixel is divided into. Must be a power of two",
+				Value:   pointindex.VectorTileInternalPixelResolution,
+			},
+			&cli.Float64Flag{
+				Name:    SIMPLIFY,
+				Aliases: []string{"simp"},
+				Usage:   "Simplify the snapped rings, removing vertices within this distance (in internal pixels). 0 is no simplification",
+			},
+			&cli.Float64Flag{
+				Name:  WIDTH,
+				Usage: "Width of the SVG in pixels",
+				Value: 1000,
+			},
+			&cli.StringFlag{
+				Name:    OUTPUT,
+				Aliases: []string{"o"},
+				Usage:   "File to write the SVG to, instead of stdout",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			tms, err := tms20.LoadTileMatrixSet(c.String(TILEMATRIXSET))
+			if err != nil {
+				return err
+			}
+			tmIDs, auto, err := parseTileMatrices(c.String(TILEMATRICES), tms)
+			if err != nil {
+				return err
+			}
+			if auto {
+				return fmt.Errorf("%s can't be %s for drawing", TILEMATRICES, autoTileMatrices)
+			}
+			slices.Sort(tmIDs)
+			gridTMID := slices.Max(tmIDs)
+			if c.IsSet(GRIDTILEMATRIX) {
+				gridTMID = c.Int(GRIDTILEMATRIX)
+			}
+			snapConfig := snap.Config{
+				KeepPointsAndLines:      c.Bool(KEEPPOINTSANDLINES),
+				InternalPixelResolution: c.Uint(INTERNALPIXELRESOLUTION),
+				SimplifyTolerance:       c.Float64(SIMPLIFY),
+			}
+			if err = snapConfig.Validate(); err != nil {
+				return err
+			}
+			if err = validateTileMatrixSet(tms, append(slices.Clone(tmIDs), gridTMID), snapConfig); err != nil {
+				return err
+			}
+			var bbox *geom.Extent
+			if c.IsSet(BBOX) {
+				bbox = new(geom.Extent)
+				if err = json.Unmarshal([]byte(c.String(BBOX)), bbox); err != nil {
+					return fmt.Errorf("could not parse bbox: %w", err)
+				}
+			}
+			var polygons []geom.Polygon
+			if c.IsSet(SOURCE) {
+				if err = checkRequiredFlags(c, TABLE); err != nil {
+					return err
+				}
+				if !c.IsSet(FID) && bbox == nil {
+					return fmt.Errorf(`flag "%s" or "%s" is required with a source GPKG`, FID, BBOX)
+				}
+				var fid *int64
+				if c.IsSet(FID) {
+					f := c.Int64(FID)
+					fid = &f
+				}
+				polygons, err = readPolygonsFromGPKG(c.String(SOURCE), c.String(TABLE), fid, bbox)
+			} else {
+				polygons, err = readGeometryArg(c)
+			}
+			if err != nil {
+				return err
+			}
+			if len(polygons) == 0 {
+				return errors.New("no (multi)polygons to draw")
+			}
+
+			drawing, err := newSnapDrawing(polygons, tms, tmIDs, gridTMID, snapConfig, bbox)
+			if err != nil {
+				return err
+			}
+			drawing.Width = c.Float64(WIDTH)
+			var sb strings.Builder
+			if err = drawing.Write(&sb); err != nil {
+				return err
+			}
+			return writeOutput(c, []byte(sb.String()))
+		},
+	}
+}
+
+// newSnapDrawing draws the polygons, snapped on every tile matrix, with the grid and the points of the grid tile matrix
+func newSnapDrawing(polygons []geom.Polygon, tms tms20.TileMatrixSet, tmIDs []tms20.TMID, gridTMID tms20.TMID,
+	snapConfig snap.Config, bbox *geom.Extent) (svg.Drawing, error) {
+	drawing := svg.Drawing{Layers: []svg.Layer{{Title: "source", Color: "black", Polygons: polygons}}}
+	newPolygonsPerTileMatrix := make(map[tms20.TMID][]geom.Polygon, len(tmIDs))
+	for _, polygon := range polygons {
+		for tmID, newPolygons := range snap.SnapPolygon(polygon, tms, tmIDs, snapConfig) {
+			newPolygonsPerTileMatrix[tmID] = append(newPolygonsPerTileMatrix[tmID], newPolygons...)
+		}
+	}
+	for i, tmID := range tmIDs {
+		drawing.Layers = append(drawing.Layers, svg.Layer{
+			Title:    fmt.Sprintf("tile matrix %d", tmID),
+			Color:    svg.Color(i),
+			Polygons: newPolygonsPerTileMatrix[tmID],
+			Fill:     true,
+		})
+	}
+
+	bottomLeft, _, err := tms.MatrixBoundingBox(gridTMID)
+	if err != nil {
+		return drawing, err
+	}
+	drawing.Grid = &svg.Grid{Origin: bottomLeft, CellSize: tms.TileMatrices[gridTMID].CellSize}
+	var ix *pointindex.PointIndex
+	internalPixelResolution := snapConfig.InternalPixelResolutionFor(gridTMID)
+	if pointindex.IsQuadTree(tms) == nil {
+		ix, err = pointindex.FromTileMatrixSet(tms, gridTMID, internalPixelResolution)
+	} else {
+		ix, err = pointindex.FromTileMatrix(tms, gridTMID, internalPixelResolution)
+	}
+	if err != nil {
+		return drawing, err
+	}
+	for _, polygon := range polygons {
+		if err = ix.InsertPolygon(polygon); err != nil {
+			slog.Warn("not drawing the points", "error", err)
+			break
+		}
+	}
+	if err == nil {
+		drawing.Points = ix.Points(ix.DeepestLevel())
+	}
+
+	if bbox != nil {
+		drawing.Extent = *bbox
+		return drawing, nil
+	}
+	extent := geom.NewExtent(polygons[0][0]...)
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			extent.AddPoints(ring...)
+		}
+	}
+	drawing.Extent = *extent.ExpandBy(max(extent.XSpan(), extent.YSpan()) * 0.05)
+	return drawing, nil
+}
+
+// readPolygonsFromGPKG reads the (multi)polygon of a feature, or of all features in the bbox, from a GPKG table
+func readPolygonsFromGPKG(path string, tableName string, fid *int64, bbox *geom.Extent) ([]geom.Polygon, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("error opening GeoPackage: %w", err)
+	}
+	source := gpkg.SourceGeopackage{}
+	source.Init(path)
+	defer source.Close()
+	tableIdx := slices.IndexFunc(source.GetTableInfo(), func(table gpkg.Table) bool { return table.Name == tableName })
+	if tableIdx < 0 {
+		return nil, fmt.Errorf("table %s not found", tableName)
+	}
+	source.Table = source.GetTableInfo()[tableIdx]
+
+	features := make(chan processing.Feature)
+	go source.ReadFeatures(features)
+	var polygons []geom.Polygon
+	for feature := range features {
+		if fid != nil && feature.FID() != *fid {
+			continue
+		}
+		if fid == nil {
+			extent, err := geom.NewExtentFromGeometry(feature.Geometry())
+			if err != nil {
+				continue
+			}
+			if _, intersects := bbox.Intersect(extent); !intersects {
+				continue
+			}
+		}
+		if featurePolygons, err := asPolygons(feature.Geometry()); err == nil {
+			polygons = append(polygons, featurePolygons...)
+		}
+	}
+	return polygons, nil
+}
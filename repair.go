@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/repair"
+)
+
+// repairSource wraps the source, so that the polygons of its features are repaired once (for all tile matrix sets)
+// before they are processed. The repairs made are recorded in the report.
+func repairSource(source processing.Source, tableName string, report *runReport) processing.Source {
+	return repairingSource{source: source, tableName: tableName, report: report}
+}
+
+type repairingSource struct {
+	source    processing.Source
+	tableName string
+	report    *runReport
+}
+
+func (s repairingSource) ReadFeatures(features chan<- processing.Feature) {
+	read := make(chan processing.Feature)
+	go s.source.ReadFeatures(read)
+	for feature := range read {
+		features <- s.repair(feature)
+	}
+	close(features)
+}
+
+// repair repairs the polygon or multipolygon of a feature.
+// A polygon that falls apart becomes a multipolygon, an empty one if nothing is left of it.
+func (s repairingSource) repair(feature processing.Feature) processing.Feature {
+	var polygons []geom.Polygon
+	switch geometry := feature.Geometry().(type) {
+	case geom.Polygon:
+		polygons = []geom.Polygon{geometry}
+	case geom.MultiPolygon:
+		for _, polygon := range geometry {
+			polygons = append(polygons, polygon)
+		}
+	default:
+		return feature
+	}
+	var repaired []geom.Polygon
+	for _, polygon := range polygons {
+		repairedPolygons, repairs := repair.Polygon(polygon)
+		if len(repairs) > 0 {
+			s.report.addRepaired(s.tableName, feature.FID(), repairs)
+		}
+		repaired = append(repaired, repairedPolygons...)
+	}
+	if _, ok := feature.Geometry().(geom.Polygon); ok && len(repaired) == 1 {
+		return repairedFeature{Feature: feature, geometry: repaired[0]}
+	}
+	multiPolygon := make(geom.MultiPolygon, 0, len(repaired))
+	for _, polygon := range repaired {
+		multiPolygon = append(multiPolygon, polygon.LinearRings())
+	}
+	return repairedFeature{Feature: feature, geometry: multiPolygon}
+}
+
+type repairedFeature struct {
+	processing.Feature
+	geometry geom.Geometry
+}
+
+func (f repairedFeature) Geometry() geom.Geometry {
+	return f.geometry
+}
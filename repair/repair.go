@@ -0,0 +1,304 @@
+// Package repair normalises (invalid) input polygons before snapping, like a makeValid.
+// It fixes what snapping can't cope with: repeated vertices, spikes, degenerate rings,
+// self-intersecting (bow-tie) rings, rings crossing each other and holes outside their shell.
+// Like the even-odd rule, what is inside an odd number of the rings is kept.
+// The winding order is left as is, snapping takes care of that.
+package repair
+
+import (
+	"sort"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/geomhelp"
+)
+
+// Repair is a kind of repair made to a polygon
+type Repair string
+
+const (
+	RepeatedVertices  Repair = "repeated vertices"
+	Spikes            Repair = "spikes"
+	DegenerateRings   Repair = "degenerate rings"
+	SelfIntersections Repair = "self-intersections"
+	CrossingRings     Repair = "crossing rings"
+	HolesOutsideShell Repair = "holes outside shell"
+)
+
+// allRepairs is the order in which the repairs are reported
+var allRepairs = []Repair{RepeatedVertices, Spikes, DegenerateRings, SelfIntersections, CrossingRings, HolesOutsideShell}
+
+// repairs are the repairs made (so far) to a polygon
+type repairs map[Repair]bool
+
+func (r repairs) list() []Repair {
+	var list []Repair
+	for _, repair := range allRepairs {
+		if r[repair] {
+			list = append(list, repair)
+		}
+	}
+	return list
+}
+
+// part is a ring while repairing a polygon
+type part struct {
+	ring [][2]float64
+	// inner is whether the ring is (a piece of) only inner rings of the polygon
+	inner bool
+	// joined is whether the ring is joined from rings that cross each other
+	joined bool
+}
+
+// Polygon repairs a polygon. This can result in multiple polygons (e.g. for a bow-tie or a hole outside the shell)
+// or none (if the outer ring is degenerate). The repairs made are returned too, nil if the polygon needed none.
+func Polygon(polygon geom.Polygon) ([]geom.Polygon, []Repair) {
+	r := repairs{}
+	var parts []part
+	for ringIdx, ring := range polygon {
+		ring = r.removeSpikes(r.removeRepeatedVertices(ring))
+		if len(ring) < 3 { // the area is checked after splitting, a bow-tie can have none
+			r[DegenerateRings] = true
+			if ringIdx == 0 {
+				return nil, r.list()
+			}
+			continue
+		}
+		parts = append(parts, part{ring: ring, inner: ringIdx > 0})
+	}
+	var pieces []part
+	hasOuter := false
+	for _, p := range r.joinCrossingRings(parts) {
+		split := r.splitSelfIntersections
+		if p.joined {
+			split = repairs{}.splitSelfIntersections // the other points where the rings cross, reported as crossing rings
+		}
+		for _, piece := range split(p.ring) {
+			if isDegenerate(piece) {
+				r[DegenerateRings] = true
+				continue
+			}
+			pieces = append(pieces, part{ring: piece, inner: p.inner, joined: p.joined})
+			hasOuter = hasOuter || !p.inner
+		}
+	}
+	if !hasOuter {
+		return nil, r.list()
+	}
+	return r.nest(pieces), r.list()
+}
+
+// removeRepeatedVertices removes consecutive duplicate vertices, and the closing vertex (which is implied)
+func (r repairs) removeRepeatedVertices(ring [][2]float64) [][2]float64 {
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	cleaned := make([][2]float64, 0, len(ring))
+	for _, vertex := range ring {
+		if len(cleaned) > 0 && vertex == cleaned[len(cleaned)-1] {
+			r[RepeatedVertices] = true
+			continue
+		}
+		cleaned = append(cleaned, vertex)
+	}
+	for len(cleaned) > 1 && cleaned[0] == cleaned[len(cleaned)-1] {
+		r[RepeatedVertices] = true
+		cleaned = cleaned[:len(cleaned)-1]
+	}
+	return cleaned
+}
+
+// removeSpikes removes the vertices where the ring turns back on itself, until there are none left
+func (r repairs) removeSpikes(ring [][2]float64) [][2]float64 {
+	for changed := true; changed && len(ring) >= 3; {
+		changed = false
+		cleaned := make([][2]float64, 0, len(ring))
+		for i, vertex := range ring {
+			previous := ring[len(ring)-1]
+			if len(cleaned) > 0 {
+				previous = cleaned[len(cleaned)-1]
+			}
+			next := ring[(i+1)%len(ring)]
+			if isSpike(previous, vertex, next) {
+				changed = true
+				continue
+			}
+			cleaned = append(cleaned, vertex)
+		}
+		if changed {
+			r[Spikes] = true
+			// removing the tip of a spike leaves its base vertices next to each other
+			ring = repairs{}.removeRepeatedVertices(cleaned)
+		}
+	}
+	return ring
+}
+
+// isSpike returns whether the segments previous-vertex and vertex-next are collinear and point in opposite directions
+func isSpike(previous, vertex, next [2]float64) bool {
+	if geomhelp.Orientation(previous, vertex, next) != 0 {
+		return false
+	}
+	dot := (vertex[0]-previous[0])*(next[0]-vertex[0]) + (vertex[1]-previous[1])*(next[1]-vertex[1])
+	return dot < 0
+}
+
+func isDegenerate(ring [][2]float64) bool {
+	return len(ring) < 3 || geomhelp.Shoelace(ring) == 0
+}
+
+// splitSelfIntersections splits a ring at the points where it crosses itself, into simple rings
+func (r repairs) splitSelfIntersections(ring [][2]float64) [][][2]float64 {
+	i, j, intersection, ok := findCrossing(ring)
+	if !ok {
+		return [][][2]float64{ring}
+	}
+	r[SelfIntersections] = true
+	// the loop between the crossing segments, and the rest
+	loop := make([][2]float64, 0, j-i+1)
+	loop = append(loop, intersection)
+	loop = append(loop, ring[i+1:j+1]...)
+	rest := make([][2]float64, 0, len(ring)-(j-i)+1)
+	rest = append(rest, ring[j+1:]...)
+	rest = append(rest, ring[:i+1]...)
+	rest = append(rest, intersection)
+	return append(r.splitSelfIntersections(loop), r.splitSelfIntersections(rest)...)
+}
+
+// findCrossing finds two segments (by the index of their first vertex, i < j) of a ring that cross each other.
+// The segments are swept by their minimal x, so only segments that overlap on the x-axis are compared.
+func findCrossing(ring [][2]float64) (int, int, [2]float64, bool) {
+	n := len(ring)
+	type segment struct {
+		idx        int
+		minX, maxX float64
+	}
+	segments := make([]segment, n)
+	for idx := range ring {
+		a, b := ring[idx], ring[(idx+1)%n]
+		segments[idx] = segment{idx: idx, minX: min(a[0], b[0]), maxX: max(a[0], b[0])}
+	}
+	sort.Slice(segments, func(a, b int) bool {
+		return segments[a].minX < segments[b].minX
+	})
+	for a := range segments {
+		for b := a + 1; b < n && segments[b].minX <= segments[a].maxX; b++ {
+			i, j := min(segments[a].idx, segments[b].idx), max(segments[a].idx, segments[b].idx)
+			if intersection, ok := geomhelp.SegmentsCross(ring[i], ring[i+1], ring[j], ring[(j+1)%n]); ok {
+				return i, j, intersection, true
+			}
+		}
+	}
+	return 0, 0, [2]float64{}, false
+}
+
+// joinCrossingRings joins every two rings that cross each other into one, at a point where they cross:
+// both rings are cut there and reconnected (going from the one to the other and back).
+// All the segments of the rings are kept (split at that point), so what is inside an odd number of them stays the same.
+// The other points where they cross are left to splitSelfIntersections.
+func (r repairs) joinCrossingRings(parts []part) []part {
+	for a := 0; a < len(parts); a++ {
+		for b := a + 1; b < len(parts); b++ {
+			i, j, intersection, ok := findRingsCrossing(parts[a].ring, parts[b].ring)
+			if !ok {
+				continue
+			}
+			r[CrossingRings] = true
+			joined := make([][2]float64, 0, len(parts[a].ring)+len(parts[b].ring)+2)
+			joined = append(joined, parts[a].ring[:i+1]...)
+			joined = append(joined, intersection)
+			joined = append(joined, parts[b].ring[j+1:]...)
+			joined = append(joined, parts[b].ring[:j+1]...)
+			joined = append(joined, intersection)
+			joined = append(joined, parts[a].ring[i+1:]...)
+			parts[a] = part{ring: joined, inner: parts[a].inner && parts[b].inner, joined: true}
+			parts = append(parts[:b], parts[b+1:]...)
+			b = a // the joined ring can cross the rings before b too
+		}
+	}
+	return parts
+}
+
+// findRingsCrossing finds a segment of ring a and one of ring b (by the index of their first vertex) that cross each other.
+// Swept like findCrossing.
+func findRingsCrossing(a, b [][2]float64) (int, int, [2]float64, bool) {
+	type segment struct {
+		inB        bool
+		idx        int
+		minX, maxX float64
+	}
+	segments := make([]segment, 0, len(a)+len(b))
+	for _, ring := range [][][2]float64{a, b} {
+		for idx := range ring {
+			p, q := ring[idx], ring[(idx+1)%len(ring)]
+			segments = append(segments, segment{inB: len(segments) >= len(a), idx: idx, minX: min(p[0], q[0]), maxX: max(p[0], q[0])})
+		}
+	}
+	sort.Slice(segments, func(s, t int) bool {
+		return segments[s].minX < segments[t].minX
+	})
+	for s := range segments {
+		for t := s + 1; t < len(segments) && segments[t].minX <= segments[s].maxX; t++ {
+			if segments[s].inB == segments[t].inB {
+				continue
+			}
+			i, j := segments[s].idx, segments[t].idx
+			if segments[s].inB {
+				i, j = j, i
+			}
+			if intersection, ok := geomhelp.SegmentsCross(a[i], a[(i+1)%len(a)], b[j], b[(j+1)%len(b)]); ok {
+				return i, j, intersection, true
+			}
+		}
+	}
+	return 0, 0, [2]float64{}, false
+}
+
+// nest makes polygons of the rings (that don't cross each other) by the even-odd rule:
+// a ring inside an odd number of the others is a hole in the innermost of those, the other rings are outer rings.
+// An inner ring of the polygon that becomes an outer ring is a hole outside its shell (unless it crossed other rings).
+func (r repairs) nest(parts []part) []geom.Polygon {
+	containers := make([][]int, len(parts))
+	for i := range parts {
+		for j := range parts {
+			if i != j && ringContainsRing(parts[j].ring, parts[i].ring) {
+				containers[i] = append(containers[i], j)
+			}
+		}
+	}
+	var polygons []geom.Polygon
+	polygonIdx := make([]int, len(parts)) // of the outer rings
+	for i, p := range parts {
+		if len(containers[i])%2 == 1 {
+			continue
+		}
+		if p.inner && !p.joined {
+			r[HolesOutsideShell] = true
+		}
+		polygonIdx[i] = len(polygons)
+		polygons = append(polygons, geom.Polygon{p.ring})
+	}
+	for i, p := range parts {
+		if len(containers[i])%2 == 0 {
+			continue
+		}
+		for _, j := range containers[i] {
+			// the innermost container, none if the ring coincides with another (which cancel each other out)
+			if len(containers[j]) == len(containers[i])-1 {
+				polygons[polygonIdx[j]] = append(polygons[polygonIdx[j]], p.ring)
+				break
+			}
+		}
+	}
+	return polygons
+}
+
+// ringContainsRing returns whether the ring contains the inner ring, which doesn't cross it.
+// Judged by the first vertex of the inner ring that is not on the boundary of the ring.
+func ringContainsRing(ring [][2]float64, inner [][2]float64) bool {
+	for _, vertex := range inner {
+		if contains, onBoundary := geomhelp.RingContains(ring, vertex); !onBoundary {
+			return contains
+		}
+	}
+	return true // all on the boundary
+}
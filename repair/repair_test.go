@@ -0,0 +1,178 @@
+package repair
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolygon(t *testing.T) {
+	tests := []struct {
+		name        string
+		polygon     geom.Polygon
+		want        []geom.Polygon
+		wantRepairs []Repair
+	}{
+		{
+			name:        "square",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}},
+			want:        []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}},
+			wantRepairs: nil,
+		},
+		{
+			name:        "closed ring",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}},
+			want:        []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}},
+			wantRepairs: nil,
+		},
+		{
+			name:        "repeated vertices",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}, {0, 0}}},
+			want:        []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}},
+			wantRepairs: []Repair{RepeatedVertices},
+		},
+		{
+			name:        "spike",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {4, 2}, {8, 2}, {4, 2}, {4, 4}, {0, 4}}},
+			want:        []geom.Polygon{{{{0, 0}, {4, 0}, {4, 2}, {4, 4}, {0, 4}}}}, // collinear vertices are fine
+			wantRepairs: []Repair{Spikes},
+		},
+		{
+			name:        "spike back along the segment",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 2}, {0, 3}}},
+			want:        []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 3}}}},
+			wantRepairs: []Repair{Spikes},
+		},
+		{
+			name:        "degenerate outer",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {8, 0}}, {{1, 1}, {2, 1}, {2, 2}}},
+			want:        nil,
+			wantRepairs: []Repair{Spikes, DegenerateRings},
+		},
+		{
+			name:        "degenerate inner",
+			polygon:     geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, {{1, 1}, {2, 1}}},
+			want:        []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}},
+			wantRepairs: []Repair{DegenerateRings},
+		},
+		{
+			name:    "bow-tie",
+			polygon: geom.Polygon{{{0, 0}, {4, 4}, {4, 0}, {0, 4}}},
+			want: []geom.Polygon{
+				{{{2, 2}, {4, 4}, {4, 0}}},
+				{{{0, 4}, {0, 0}, {2, 2}}},
+			},
+			wantRepairs: []Repair{SelfIntersections},
+		},
+		{
+			name: "bow-tie with a hole in one half",
+			polygon: geom.Polygon{
+				{{0, 0}, {8, 8}, {8, 0}, {0, 8}},
+				{{6, 3}, {6, 5}, {7, 4}},
+			},
+			want: []geom.Polygon{
+				{{{4, 4}, {8, 8}, {8, 0}}, {{6, 3}, {6, 5}, {7, 4}}},
+				{{{0, 8}, {0, 0}, {4, 4}}},
+			},
+			wantRepairs: []Repair{SelfIntersections},
+		},
+		{
+			name: "hole outside shell",
+			polygon: geom.Polygon{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+				{{5, 1}, {5, 2}, {6, 2}, {6, 1}},
+			},
+			want: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}},
+				{{{5, 1}, {5, 2}, {6, 2}, {6, 1}}},
+			},
+			wantRepairs: []Repair{HolesOutsideShell},
+		},
+		{
+			name: "hole crossing the shell",
+			polygon: geom.Polygon{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+				{{3, 1}, {3, 3}, {6, 3}, {6, 1}},
+			},
+			want: []geom.Polygon{
+				{{{4, 1}, {4, 3}, {6, 3}, {6, 1}}}, // the part outside the shell, like a hole outside the shell
+				{{{3, 1}, {3, 3}, {4, 3}, {4, 4}, {0, 4}, {0, 0}, {4, 0}, {4, 1}}},
+			},
+			wantRepairs: []Repair{CrossingRings},
+		},
+		{
+			name: "hole crossing another hole",
+			polygon: geom.Polygon{
+				{{0, 0}, {8, 0}, {8, 8}, {0, 8}},
+				{{1, 1}, {1, 4}, {4, 4}, {4, 1}},
+				{{3, 3}, {3, 6}, {6, 6}, {6, 3}},
+			},
+			want: []geom.Polygon{
+				{{{0, 0}, {8, 0}, {8, 8}, {0, 8}}, {{4, 1}, {1, 1}, {1, 4}, {3, 4}, {3, 6}, {6, 6}, {6, 3}, {4, 3}}},
+				{{{4, 3}, {3, 3}, {3, 4}, {4, 4}}}, // inside both holes, so inside three rings
+			},
+			wantRepairs: []Repair{CrossingRings},
+		},
+		{
+			name:    "outer ring looping inside itself",
+			polygon: geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 5}, {7, 5}, {7, 8}, {3, 8}, {3, 2}, {0, 2}}},
+			want: []geom.Polygon{
+				{{{3, 2}, {0, 2}, {0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 5}, {3, 5}}, {{3, 5}, {7, 5}, {7, 8}, {3, 8}}},
+			},
+			wantRepairs: []Repair{SelfIntersections},
+		},
+		{
+			name: "hole containing the shell",
+			polygon: geom.Polygon{
+				{{2, 2}, {4, 2}, {4, 4}, {2, 4}},
+				{{0, 0}, {6, 0}, {6, 6}, {0, 6}},
+			},
+			want: []geom.Polygon{
+				{{{0, 0}, {6, 0}, {6, 6}, {0, 6}}, {{2, 2}, {4, 2}, {4, 4}, {2, 4}}},
+			},
+			wantRepairs: []Repair{HolesOutsideShell},
+		},
+		{
+			name: "hole touching the shell",
+			polygon: geom.Polygon{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+				{{0, 1}, {1, 2}, {1, 1}},
+			},
+			want: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, {{0, 1}, {1, 2}, {1, 1}}},
+			},
+			wantRepairs: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotRepairs := Polygon(tt.polygon)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRepairs, gotRepairs)
+		})
+	}
+}
+
+func TestFindRingsCrossing(t *testing.T) {
+	i, j, intersection, ok := findRingsCrossing([][2]float64{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, [][2]float64{{3, 1}, {3, 3}, {6, 3}, {6, 1}})
+	assert.True(t, ok)
+	assert.Equal(t, 1, i)
+	assert.Contains(t, []int{1, 3}, j)
+	assert.Contains(t, [][2]float64{{4, 1}, {4, 3}}, intersection)
+
+	_, _, _, ok = findRingsCrossing([][2]float64{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, [][2]float64{{1, 1}, {1, 2}, {2, 2}, {2, 1}})
+	assert.False(t, ok)
+}
+
+func TestFindCrossing(t *testing.T) {
+	ring := [][2]float64{{0, 10}, {10, 10}, {0, 0}, {10, 0}}
+	i, j, intersection, ok := findCrossing(ring)
+	assert.True(t, ok)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, 3, j)
+	assert.Equal(t, [2]float64{5, 5}, intersection)
+
+	_, _, _, ok = findCrossing([][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}})
+	assert.False(t, ok)
+}
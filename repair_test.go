@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/repair"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFeature struct {
+	fid      int64
+	geometry geom.Geometry
+}
+
+func (f testFeature) FID() int64 {
+	return f.fid
+}
+
+func (f testFeature) Columns() []interface{} {
+	return nil
+}
+
+func (f testFeature) Geometry() geom.Geometry {
+	return f.geometry
+}
+
+func TestRepairingSource_repair(t *testing.T) {
+	square := geom.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}
+	bowTie := geom.Polygon{{{0, 0}, {4, 4}, {4, 0}, {0, 4}}}
+	tests := []struct {
+		name        string
+		geometry    geom.Geometry
+		want        geom.Geometry
+		wantRepairs []repair.Repair
+	}{
+		{name: "valid polygon", geometry: square, want: square},
+		{name: "polygon falling apart", geometry: bowTie, want: geom.MultiPolygon{
+			{{{2, 2}, {4, 4}, {4, 0}}},
+			{{{0, 4}, {0, 0}, {2, 2}}},
+		}, wantRepairs: []repair.Repair{repair.SelfIntersections}},
+		{name: "nothing left of a polygon", geometry: geom.Polygon{{{0, 0}, {4, 0}, {8, 0}}}, want: geom.MultiPolygon{},
+			wantRepairs: []repair.Repair{repair.Spikes, repair.DegenerateRings}},
+		{name: "multipolygon", geometry: geom.MultiPolygon{square, bowTie}, want: geom.MultiPolygon{
+			square,
+			{{{2, 2}, {4, 4}, {4, 0}}},
+			{{{0, 4}, {0, 0}, {2, 2}}},
+		}, wantRepairs: []repair.Repair{repair.SelfIntersections}},
+		{name: "point", geometry: geom.Point{1, 2}, want: geom.Point{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newRunReport()
+			s := repairSource(nil, "table", report).(repairingSource)
+
+			got := s.repair(testFeature{fid: 7, geometry: tt.geometry})
+			assert.Equal(t, int64(7), got.FID())
+			assert.Equal(t, tt.want, got.Geometry())
+			if tt.wantRepairs == nil {
+				assert.Nil(t, report.Tables["table"])
+			} else {
+				assert.Equal(t, map[int64][]repair.Repair{7: tt.wantRepairs}, report.Tables["table"].Repairs)
+			}
+		})
+	}
+}
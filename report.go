@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/pointindex"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/repair"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+	"github.com/pdok/texel/validate"
+	"golang.org/x/exp/maps"
+)
+
+// runReport collects the figures and notable features of a run, which are logged when done
+// and can be written as JSON (for a pipeline to decide whether to publish the result).
+// A nil report records nothing.
+type runReport struct {
+	mu      sync.Mutex
+	Started time.Time `json:"started"`
+	Seconds float64   `json:"seconds"`
+	// Stages are the wall times of the stages of the run, in order
+	Stages []stageReport `json:"stages"`
+	// Tables are the figures of the source tables, by name
+	Tables map[string]*sourceTableReport `json:"tables"`
+	// TileMatrixSets are the figures of snapping the source tables, by tile matrix set ID
+	TileMatrixSets map[string]*tileMatrixSetReport `json:"tileMatrixSets"`
+}
+
+type stageReport struct {
+	Name    string    `json:"name"`
+	Seconds float64   `json:"seconds"`
+	started time.Time // while running
+}
+
+type sourceTableReport struct {
+	FeaturesIn    uint64 `json:"featuresIn"`
+	NonPolygons   uint64 `json:"nonPolygons"`
+	MultiPolygons uint64 `json:"multiPolygons"`
+	// Repairs are the repairs made to the features, by fid
+	Repairs map[int64][]repair.Repair `json:"repairs"`
+}
+
+type tileMatrixSetReport struct {
+	Deviation deviationReport `json:"deviation"`
+	// SkippedTables are left out because their SRS does not match the CRS of the tile matrix set
+	SkippedTables []string `json:"skippedTables"`
+	// Tables are the figures of snapping the source tables, by name
+	Tables map[string]*tableReport `json:"tables"`
+}
+
+// deviationReport is the deviation of the internal pixel grid on the deepest tile matrix, see pointindex.DeviationStats
+type deviationReport struct {
+	TileMatrixID            tms20.TMID `json:"tileMatrixId"`
+	InternalPixelResolution uint       `json:"internalPixelResolution"`
+	InUnits                 float64    `json:"inUnits"`
+	InPixels                float64    `json:"inPixels"`
+	Stats                   string     `json:"stats"`
+}
+
+type tableReport struct {
+	VerticesIn uint64 `json:"verticesIn"`
+	// ClippedFIDs are the features that were clipped to the grid
+	ClippedFIDs []int64 `json:"clippedFids"`
+	// IgnoredFIDs are the features that were (partly) left out, because they fall outside the grid
+	IgnoredFIDs []int64 `json:"ignoredFids"`
+	// Failures are the findings of validating the snapped polygons
+	Failures []failureReport `json:"failures"`
+	// TileMatrices are the figures per tile matrix
+	TileMatrices map[tms20.TMID]*tileMatrixReport `json:"tileMatrices"`
+	stats        *snap.Stats
+}
+
+type failureReport struct {
+	FID          int64      `json:"fid"`
+	TileMatrixID tms20.TMID `json:"tileMatrixId"`
+	Kind         string     `json:"kind"`
+	Location     geom.Point `json:"location"`
+}
+
+type tileMatrixReport struct {
+	FeaturesOut uint64 `json:"featuresOut"`
+	// DroppedFeatures are left out entirely, because snapping left nothing of them
+	DroppedFeatures uint64 `json:"droppedFeatures"`
+	// CollapsedRings, RemovedOuters and FilledInners are the rings removed by snapping, see snap.Stats
+	CollapsedRings uint64 `json:"collapsedRings"`
+	RemovedOuters  uint64 `json:"removedOuters"`
+	FilledInners   uint64 `json:"filledInners"`
+	VerticesOut    uint64 `json:"verticesOut"`
+	// Quarantined are the features left out because they are invalid after snapping
+	Quarantined uint64 `json:"quarantined"`
+}
+
+func newRunReport() *runReport {
+	return &runReport{
+		Started:        time.Now(),
+		Tables:         make(map[string]*sourceTableReport),
+		TileMatrixSets: make(map[string]*tileMatrixSetReport),
+	}
+}
+
+// startStage ends the current stage (if any) and starts the next one
+func (r *runReport) startStage(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endStage()
+	r.Stages = append(r.Stages, stageReport{Name: name, started: time.Now()})
+}
+
+// finish ends the current stage (if any) and the run
+func (r *runReport) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endStage()
+	r.Seconds = time.Since(r.Started).Seconds()
+}
+
+func (r *runReport) endStage() {
+	if len(r.Stages) == 0 {
+		return
+	}
+	if stage := &r.Stages[len(r.Stages)-1]; stage.Seconds == 0 {
+		stage.Seconds = time.Since(stage.started).Seconds()
+	}
+}
+
+// addTileMatrixSet records the deviation and the skipped tables of a tile matrix set
+func (r *runReport) addTileMatrixSet(tmsID string, deviation deviationReport, skippedTables []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tmsReport := r.tileMatrixSet(tmsID)
+	tmsReport.Deviation = deviation
+	tmsReport.SkippedTables = skippedTables
+}
+
+// statsFor returns the stats to count the rings removed by snapping a table for a tile matrix set in
+func (r *runReport) statsFor(tmsID string, tableName string) *snap.Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.table(tmsID, tableName).stats
+}
+
+// addRepaired records the repairs made to (a part of) a feature
+func (r *runReport) addRepaired(tableName string, fid int64, repairs []repair.Repair) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sourceTable := r.sourceTable(tableName)
+	if sourceTable.Repairs == nil {
+		sourceTable.Repairs = make(map[int64][]repair.Repair)
+	}
+	for _, rep := range repairs {
+		if !slices.Contains(sourceTable.Repairs[fid], rep) {
+			sourceTable.Repairs[fid] = append(sourceTable.Repairs[fid], rep)
+		}
+	}
+}
+
+// addClipped records that a (part of a) feature was clipped to the grid of a tile matrix set
+func (r *runReport) addClipped(tmsID string, tableName string, fid int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.table(tmsID, tableName)
+	table.ClippedFIDs = appendFID(table.ClippedFIDs, fid)
+}
+
+// addIgnored records that a (part of a) feature was left out, because it falls outside the grid of a tile matrix set
+func (r *runReport) addIgnored(tmsID string, tableName string, fid int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.table(tmsID, tableName)
+	table.IgnoredFIDs = appendFID(table.IgnoredFIDs, fid)
+}
+
+// addSnapped records the vertices before and after snapping (a part of) a feature
+func (r *runReport) addSnapped(tmsID string, tableName string, polygon geom.Polygon, newPolygonsPerTileMatrix map[tms20.TMID][]geom.Polygon) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.table(tmsID, tableName)
+	table.VerticesIn += countVertices(polygon)
+	for tmID, newPolygons := range newPolygonsPerTileMatrix {
+		for _, newPolygon := range newPolygons {
+			table.tileMatrix(tmID).VerticesOut += countVertices(newPolygon)
+		}
+	}
+}
+
+// addValidated records the findings of validating a snapped feature, and for which tile matrices it was quarantined
+func (r *runReport) addValidated(tmsID string, tableName string, validPolygonsPerTileMatrix map[tms20.TMID][]geom.Polygon, findings []validate.Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.table(tmsID, tableName)
+	quarantined := make(map[tms20.TMID]bool)
+	for _, finding := range findings {
+		table.Failures = append(table.Failures, failureReport{
+			FID:          finding.FID,
+			TileMatrixID: finding.TileMatrixID,
+			Kind:         string(finding.Kind),
+			Location:     finding.Location,
+		})
+		if _, ok := validPolygonsPerTileMatrix[finding.TileMatrixID]; !ok && !quarantined[finding.TileMatrixID] {
+			quarantined[finding.TileMatrixID] = true
+			table.tileMatrix(finding.TileMatrixID).Quarantined++
+		}
+	}
+}
+
+// addCounts records the counts of processing a table, for the tile matrix sets (by ID) it was processed for
+func (r *runReport) addCounts(tableName string, tileMatrixIDsByTMS map[string][]tms20.TMID, counts processing.Counts) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sourceTable := r.sourceTable(tableName)
+	sourceTable.FeaturesIn = counts.FeaturesIn
+	sourceTable.NonPolygons = counts.NonPolygons
+	sourceTable.MultiPolygons = counts.MultiPolygons
+	for tmsID, tmIDs := range tileMatrixIDsByTMS {
+		table := r.table(tmsID, tableName)
+		for _, tmID := range tmIDs {
+			tmReport := table.tileMatrix(tmID)
+			tmReport.FeaturesOut = counts.FeaturesOut[processing.TargetKey{TileMatrixSetID: tmsID, TileMatrixID: tmID}]
+			tmReport.DroppedFeatures = counts.FeaturesIn - tmReport.FeaturesOut // other geometries are always kept
+			tmReport.CollapsedRings = table.stats.CollapsedRings[tmID]
+			tmReport.RemovedOuters = table.stats.RemovedOuters[tmID]
+			tmReport.FilledInners = table.stats.FilledInners[tmID]
+		}
+	}
+}
+
+// write writes the report as JSON
+func (r *runReport) write(reportPath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reportJSON, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(reportPath, reportJSON, 0o644) //nolint:gosec
+}
+
+// log logs the counts, and the fids at debug level (the JSON report has them all)
+func (r *runReport) log() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tmsIDs := maps.Keys(r.TileMatrixSets)
+	sort.Strings(tmsIDs)
+	for _, tmsID := range tmsIDs {
+		tmsReport := r.TileMatrixSets[tmsID]
+		if len(tmsReport.SkippedTables) > 0 {
+			slog.Info("skipped tables (srs mismatch)", "tms", tmsID, "tables", tmsReport.SkippedTables)
+		}
+		tableNames := maps.Keys(tmsReport.Tables)
+		sort.Strings(tableNames)
+		for _, tableName := range tableNames {
+			table := tmsReport.Tables[tableName]
+			if len(table.ClippedFIDs) > 0 {
+				slog.Info("clipped features (outside the grid)", "tms", tmsID, "table", tableName, "count", len(table.ClippedFIDs))
+				slog.Debug("clipped features (outside the grid)", "tms", tmsID, "table", tableName, "fids", table.ClippedFIDs)
+			}
+			if len(table.IgnoredFIDs) > 0 {
+				slog.Info("ignored features (outside the grid)", "tms", tmsID, "table", tableName, "count", len(table.IgnoredFIDs))
+				slog.Debug("ignored features (outside the grid)", "tms", tmsID, "table", tableName, "fids", table.IgnoredFIDs)
+			}
+			tmIDs := maps.Keys(table.TileMatrices)
+			slices.Sort(tmIDs)
+			for _, tmID := range tmIDs {
+				tmReport := table.TileMatrices[tmID]
+				if tmReport.RemovedOuters > 0 || tmReport.FilledInners > 0 {
+					slog.Info("small rings", "tms", tmsID, "table", tableName, "tmID", tmID,
+						"removedOuters", tmReport.RemovedOuters, "filledInners", tmReport.FilledInners)
+				}
+			}
+		}
+	}
+	tableNames := maps.Keys(r.Tables)
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		repairs := r.Tables[tableName].Repairs
+		if len(repairs) == 0 {
+			continue
+		}
+		slog.Info("repaired features", "table", tableName, "count", len(repairs))
+		fids := maps.Keys(repairs)
+		slices.Sort(fids)
+		for _, fid := range fids {
+			slog.Debug("repaired feature", "table", tableName, "fid", fid, "repairs", repairs[fid])
+		}
+	}
+}
+
+// sourceTable returns the report of a source table, creating it if needed. Needs the lock.
+func (r *runReport) sourceTable(tableName string) *sourceTableReport {
+	if r.Tables[tableName] == nil {
+		r.Tables[tableName] = &sourceTableReport{}
+	}
+	return r.Tables[tableName]
+}
+
+// tileMatrixSet returns the report of a tile matrix set, creating it if needed. Needs the lock.
+func (r *runReport) tileMatrixSet(tmsID string) *tileMatrixSetReport {
+	if r.TileMatrixSets[tmsID] == nil {
+		r.TileMatrixSets[tmsID] = &tileMatrixSetReport{Tables: make(map[string]*tableReport)}
+	}
+	return r.TileMatrixSets[tmsID]
+}
+
+// table returns the report of snapping a table for a tile matrix set, creating it if needed. Needs the lock.
+func (r *runReport) table(tmsID string, tableName string) *tableReport {
+	tmsReport := r.tileMatrixSet(tmsID)
+	if tmsReport.Tables[tableName] == nil {
+		tmsReport.Tables[tableName] = &tableReport{TileMatrices: make(map[tms20.TMID]*tileMatrixReport), stats: snap.NewStats()}
+	}
+	return tmsReport.Tables[tableName]
+}
+
+func (t *tableReport) tileMatrix(tmID tms20.TMID) *tileMatrixReport {
+	if t.TileMatrices[tmID] == nil {
+		t.TileMatrices[tmID] = &tileMatrixReport{}
+	}
+	return t.TileMatrices[tmID]
+}
+
+// newDeviationReport reports the deviation of the internal pixel grid on the deepest of the tile matrices
+func newDeviationReport(tms tms20.TileMatrixSet, tileMatrixIDs []tms20.TMID, snapConfig snap.Config) (deviationReport, error) {
+	deepestTMID := snapConfig.DeepestTileMatrix(tms, tileMatrixIDs)
+	internalPixelResolution := snapConfig.InternalPixelResolutionFor(deepestTMID)
+	stats, deviationInUnits, deviationInPixels, err := pointindex.DeviationStats(tms, deepestTMID, internalPixelResolution)
+	return deviationReport{
+		TileMatrixID:            deepestTMID,
+		InternalPixelResolution: internalPixelResolution,
+		InUnits:                 deviationInUnits,
+		InPixels:                deviationInPixels,
+		Stats:                   stats,
+	}, err
+}
+
+// appendFID appends the fid, unless it's the last one already (another part of the same multipolygon)
+func appendFID(fids []int64, fid int64) []int64 {
+	if len(fids) > 0 && fids[len(fids)-1] == fid {
+		return fids
+	}
+	return append(fids, fid)
+}
+
+func countVertices(polygon geom.Polygon) uint64 {
+	var vertices uint64
+	for _, ring := range polygon {
+		vertices += uint64(len(ring))
+	}
+	return vertices
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/tms20"
+	"github.com/pdok/texel/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReport_write(t *testing.T) {
+	r := newRunReport()
+	r.startStage("reading")
+	time.Sleep(10 * time.Millisecond)
+	r.startStage("snapping table")
+
+	square := geom.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 10}}}
+	r.addSnapped("tms", "table", square, map[tms20.TMID][]geom.Polygon{5: {square}, 6: {square, square}})
+	r.addClipped("tms", "table", 1)
+	r.addClipped("tms", "table", 1) // another part of the same multipolygon
+	r.addClipped("tms", "table", 2)
+	r.addIgnored("tms", "table", 3)
+	// feature 7 is invalid twice on tile matrix 6 and quarantined there, and valid on 5
+	r.addValidated("tms", "table", map[tms20.TMID][]geom.Polygon{5: {square}}, []validate.Finding{
+		{Kind: validate.SelfIntersection, FID: 7, TileMatrixID: 6, Location: geom.Point{1, 2}},
+		{Kind: validate.RingCrossing, FID: 7, TileMatrixID: 6, Location: geom.Point{3, 4}},
+	})
+	// feature 8 is invalid on tile matrix 5, but kept in the (default) warn mode
+	r.addValidated("tms", "table", map[tms20.TMID][]geom.Polygon{5: {square}, 6: {square}}, []validate.Finding{
+		{Kind: validate.SelfIntersection, FID: 8, TileMatrixID: 5, Location: geom.Point{5, 6}},
+	})
+	r.statsFor("tms", "table").RemovedOuters[6] = 2
+	r.addCounts("table", map[string][]tms20.TMID{"tms": {5, 6}}, processing.Counts{
+		FeaturesIn:    10,
+		NonPolygons:   1,
+		MultiPolygons: 2,
+		FeaturesKept:  9,
+		FeaturesOut: map[processing.TargetKey]uint64{
+			{TileMatrixSetID: "tms", TileMatrixID: 5}: 10,
+			{TileMatrixSetID: "tms", TileMatrixID: 6}: 7,
+		},
+	})
+	r.finish()
+
+	reportPath := path.Join(t.TempDir(), "report.json")
+	require.NoError(t, r.write(reportPath))
+	reportJSON, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+	var got struct {
+		Seconds        float64                    `json:"seconds"`
+		Stages         []map[string]any           `json:"stages"`
+		Tables         map[string]json.RawMessage `json:"tables"`
+		TileMatrixSets map[string]struct {
+			Tables map[string]json.RawMessage `json:"tables"`
+		} `json:"tileMatrixSets"`
+	}
+	require.NoError(t, json.Unmarshal(reportJSON, &got))
+
+	require.Len(t, got.Stages, 2)
+	assert.Equal(t, "reading", got.Stages[0]["name"])
+	assert.GreaterOrEqual(t, got.Stages[0]["seconds"], 0.01)
+	assert.Equal(t, "snapping table", got.Stages[1]["name"])
+	assert.Greater(t, got.Stages[1]["seconds"], 0.0)
+	assert.GreaterOrEqual(t, got.Seconds, got.Stages[0]["seconds"].(float64)+got.Stages[1]["seconds"].(float64))
+
+	assert.JSONEq(t, `{"featuresIn": 10, "nonPolygons": 1, "multiPolygons": 2, "repairs": null}`, string(got.Tables["table"]))
+	assert.JSONEq(t, `{
+		"verticesIn": 4,
+		"clippedFids": [1, 2],
+		"ignoredFids": [3],
+		"failures": [
+			{"fid": 7, "tileMatrixId": 6, "kind": "self-intersection", "location": [1, 2]},
+			{"fid": 7, "tileMatrixId": 6, "kind": "ring crossing", "location": [3, 4]},
+			{"fid": 8, "tileMatrixId": 5, "kind": "self-intersection", "location": [5, 6]}
+		],
+		"tileMatrices": {
+			"5": {"featuresOut": 10, "droppedFeatures": 0, "collapsedRings": 0, "removedOuters": 0, "filledInners": 0, "verticesOut": 4, "quarantined": 0},
+			"6": {"featuresOut": 7, "droppedFeatures": 3, "collapsedRings": 0, "removedOuters": 2, "filledInners": 0, "verticesOut": 8, "quarantined": 1}
+		}
+	}`, string(got.TileMatrixSets["tms"].Tables["table"]))
+}
+
+func TestAppendFID(t *testing.T) {
+	tests := []struct {
+		name string
+		fids []int64
+		fid  int64
+		want []int64
+	}{
+		{name: "first", fids: nil, fid: 1, want: []int64{1}},
+		{name: "next", fids: []int64{1}, fid: 2, want: []int64{1, 2}},
+		{name: "same as the last", fids: []int64{1, 2}, fid: 2, want: []int64{1, 2}},
+		{name: "same as an earlier one", fids: []int64{1, 2}, fid: 1, want: []int64{1, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, appendFID(tt.fids, tt.fid))
+		})
+	}
+}
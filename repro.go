@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkt"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+	"github.com/urfave/cli/v2"
+)
+
+// repro is a self-contained reproduction of a panic while snapping a feature, to replay with texel repro
+type repro struct {
+	Panic string `json:"panic"`
+	Table string `json:"table"`
+	FID   int64  `json:"fid"`
+	// Polygon (as passed to the processing, so after repairing and reprojecting) as WKT, with full precision
+	Polygon       string               `json:"polygon"`
+	TileMatrixSet *tms20.TileMatrixSet `json:"tileMatrixSet"` // pointer, for its (un)marshalling
+	TileMatrixIDs []tms20.TMID         `json:"tileMatrixIds"`
+	SnapConfig    snap.Config          `json:"snapConfig"`
+	Stack         string               `json:"stack"`
+}
+
+// writeRepro writes a reproduction of the panic to the directory and returns the error to fail with
+func writeRepro(dir string, tableName string, tms tms20.TileMatrixSet, options processingOptions, panicErr processing.PanicError) error {
+	polygonWKT, err := encodeWKT(panicErr.Polygon)
+	if err != nil {
+		return fmt.Errorf("%w (could not write a reproduction: %v)", panicErr, err)
+	}
+	reproJSON, err := json.MarshalIndent(repro{
+		Panic:         fmt.Sprint(panicErr.Recovered),
+		Table:         tableName,
+		FID:           panicErr.FID,
+		Polygon:       polygonWKT,
+		TileMatrixSet: &tms,
+		TileMatrixIDs: panicErr.TileMatrixIDs,
+		SnapConfig:    options.snapConfig,
+		Stack:         string(panicErr.Stack),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w (could not write a reproduction: %v)", panicErr, err)
+	}
+	reproPath := path.Join(dir, unsafeFileNameCharsRegex.ReplaceAllString(fmt.Sprintf("repro_%s_%s_%d", tableName, tms.ID, panicErr.FID), "_")+".json")
+	if err = os.MkdirAll(dir, 0o755); err == nil {
+		err = os.WriteFile(reproPath, reproJSON, 0o644) //nolint:gosec
+	}
+	if err != nil {
+		return fmt.Errorf("%w (could not write a reproduction: %v)", panicErr, err)
+	}
+	return fmt.Errorf("%w (reproduction written to %s, replay with: texel repro %s)", panicErr, reproPath, reproPath)
+}
+
+// encodeWKT encodes a polygon as WKT, without losing precision
+func encodeWKT(polygon geom.Polygon) (string, error) {
+	var sb strings.Builder
+	if err := wkt.NewEncoder(&sb, false, -1, 'f').Encode(polygon); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func reproCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "repro",
+		Usage:     "Replay the snapping of a feature that panicked, from the reproduction file written by the run",
+		ArgsUsage: "<file>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return errors.New("expected one reproduction file")
+			}
+			r, err := loadRepro(c.Args().First())
+			if err != nil {
+				return err
+			}
+			slog.Info("replaying", "table", r.Table, "fid", r.FID, "panic", r.Panic)
+			newPolygonsPerTileMatrix, err := r.replay()
+			if err != nil {
+				return err
+			}
+			slog.Info("no panic")
+			for _, tmID := range r.TileMatrixIDs {
+				for _, newPolygon := range newPolygonsPerTileMatrix[tmID] {
+					if polygonWKT, err := encodeWKT(newPolygon); err == nil {
+						fmt.Printf("%d: %s\n", tmID, polygonWKT)
+					} else { // a point or line (keeppointsandlines)
+						fmt.Printf("%d: %v\n", tmID, newPolygon)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func loadRepro(reproPath string) (repro, error) {
+	var r repro
+	reproJSON, err := os.ReadFile(reproPath)
+	if err != nil {
+		return r, err
+	}
+	if err = json.Unmarshal(reproJSON, &r); err != nil {
+		return r, fmt.Errorf("could not parse reproduction %s: %w", reproPath, err)
+	}
+	if r.TileMatrixSet == nil {
+		return r, fmt.Errorf("reproduction %s has no tile matrix set", reproPath)
+	}
+	return r, nil
+}
+
+// replay snaps the polygon again, the same way the run did
+func (r repro) replay() (map[tms20.TMID][]geom.Polygon, error) {
+	geometry, err := wkt.DecodeString(r.Polygon)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the polygon: %w", err)
+	}
+	polygon, ok := geometry.(geom.Polygon)
+	if !ok {
+		return nil, fmt.Errorf("expected a polygon, got %T", geometry)
+	}
+	return snap.SnapPolygon(polygon, *r.TileMatrixSet, r.TileMatrixIDs, r.SnapConfig), nil
+}
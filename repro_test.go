@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepro_roundTrip(t *testing.T) {
+	tests := []struct {
+		tmsID   string
+		polygon geom.Polygon
+	}{
+		{tmsID: "NetherlandsRDNewQuad", polygon: geom.Polygon{{{155000, 463000}, {155100, 463000}, {155100, 463100}, {155000, 463100}}}},
+		{tmsID: "WebMercatorQuad", polygon: geom.Polygon{{{543210.5, 6864321.25}, {543310.5, 6864321.25}, {543310.5, 6864421.25}, {543210.5, 6864421.25}}}},
+		{tmsID: "EuropeanETRS89_LAEAQuad", polygon: geom.Polygon{{{4321000.125, 3210000}, {4321100, 3210000}, {4321100, 3210100.5}, {4321000.125, 3210100.5}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmsID, func(t *testing.T) {
+			tms, err := tms20.LoadEmbeddedTileMatrixSet(tt.tmsID)
+			require.NoError(t, err)
+			dir := t.TempDir()
+			tmIDs := []tms20.TMID{10, 14}
+			options := processingOptions{snapConfig: snap.Config{KeepPointsAndLines: true}}
+			panicErr := processing.PanicError{FID: 42, Polygon: tt.polygon, TileMatrixIDs: tmIDs, Recovered: errors.New("boom")}
+
+			err = writeRepro(dir, "table", tms, options, panicErr)
+			require.ErrorAs(t, err, &processing.PanicError{})
+			reproPath := path.Join(dir, fmt.Sprintf("repro_table_%s_42.json", tt.tmsID))
+			require.ErrorContains(t, err, reproPath)
+
+			r, err := loadRepro(reproPath)
+			require.NoError(t, err)
+			assert.Equal(t, "boom", r.Panic)
+			assert.Equal(t, int64(42), r.FID)
+			assert.Equal(t, tmIDs, r.TileMatrixIDs)
+			newPolygonsPerTileMatrix, err := r.replay()
+			require.NoError(t, err)
+			assert.Equal(t, snap.SnapPolygon(tt.polygon, tms, tmIDs, options.snapConfig), newPolygonsPerTileMatrix)
+		})
+	}
+}
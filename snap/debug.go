@@ -0,0 +1,110 @@
+package snap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/pointindex"
+	"github.com/pdok/texel/tms20"
+)
+
+// Debug collects the intermediate stages of snapping a polygon, for finding out why it comes out wrong.
+// Set Config.Debug to enable it. Not safe for concurrent use, use one per feature.
+type Debug struct {
+	Stages []DebugStage
+}
+
+// DebugStage is an intermediate result of snapping
+type DebugStage struct {
+	// Name of the stage, including the level, ring or tile matrix it applies to. E.g. "raw ring level 14 ring 0"
+	Name string
+	// Geometry of the stage, nil for the point index
+	Geometry geom.Geometry
+	// WKT of the point index (quadrants), only for the point index stage
+	WKT string
+}
+
+func (d *Debug) add(name string, geometry geom.Geometry) {
+	if d == nil {
+		return
+	}
+	d.Stages = append(d.Stages, DebugStage{Name: name, Geometry: geometry})
+}
+
+func (d *Debug) addInput(polygon geom.Polygon) {
+	if d == nil {
+		return
+	}
+	d.add("input", copyPolygon(polygon))
+}
+
+func (d *Debug) addPointIndex(ix *pointindex.PointIndex) {
+	if d == nil {
+		return
+	}
+	var sb strings.Builder
+	ix.ToWkt(&sb)
+	d.Stages = append(d.Stages, DebugStage{Name: "point index", WKT: sb.String()})
+}
+
+func (d *Debug) addRing(name string, level pointindex.Level, ringIdx int, ring [][2]float64) {
+	if d == nil {
+		return
+	}
+	d.add(fmt.Sprintf("%s level %d ring %d", name, level, ringIdx), ringAsLineString(ring))
+}
+
+func (d *Debug) addRings(name string, level pointindex.Level, ringIdx int, rings [][][2]float64) {
+	if d == nil || len(rings) == 0 {
+		return
+	}
+	d.add(fmt.Sprintf("%s level %d ring %d", name, level, ringIdx), ringsAsMultiLineString(rings))
+}
+
+func (d *Debug) addOutersInners(name string, level pointindex.Level, outers, inners [][][2]float64) {
+	if d == nil {
+		return
+	}
+	d.add(fmt.Sprintf("%s outers level %d", name, level), ringsAsMultiLineString(outers))
+	d.add(fmt.Sprintf("%s inners level %d", name, level), ringsAsMultiLineString(inners))
+}
+
+func (d *Debug) addFinal(tmID tms20.TMID, polygons []geom.Polygon) {
+	if d == nil {
+		return
+	}
+	multiPolygon := make(geom.MultiPolygon, 0, len(polygons))
+	for _, polygon := range polygons {
+		multiPolygon = append(multiPolygon, copyPolygon(polygon))
+	}
+	d.add(fmt.Sprintf("final tile matrix %d", tmID), multiPolygon)
+}
+
+// ringAsLineString returns a (closed) copy of the ring, which can have less than three vertices
+func ringAsLineString(ring [][2]float64) geom.LineString {
+	lineString := make(geom.LineString, len(ring), len(ring)+1)
+	copy(lineString, ring)
+	if len(ring) > 1 && ring[0] != ring[len(ring)-1] {
+		lineString = append(lineString, ring[0])
+	}
+	return lineString
+}
+
+func ringsAsMultiLineString(rings [][][2]float64) geom.MultiLineString {
+	multiLineString := make(geom.MultiLineString, len(rings))
+	for i, ring := range rings {
+		multiLineString[i] = ringAsLineString(ring)
+	}
+	return multiLineString
+}
+
+// copyPolygon copies the polygon, because the stages are kept while snapping continues
+func copyPolygon(polygon geom.Polygon) geom.Polygon {
+	polygonCopy := make(geom.Polygon, len(polygon))
+	for i, ring := range polygon {
+		polygonCopy[i] = make([][2]float64, len(ring))
+		copy(polygonCopy[i], ring)
+	}
+	return polygonCopy
+}
@@ -0,0 +1,121 @@
+package snap
+
+import (
+	"math"
+	"slices"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/geomhelp"
+	"github.com/pdok/texel/pointindex"
+)
+
+// simplifyPolygons removes the vertices from the snapped rings that lie within the tolerance (in internal pixels)
+// of the shortcut between their neighbours, if the shortcut does not cross or touch any other point in the PointIndex.
+// Only vertices that are used once (in all rings on the level) are removed,
+// because only their own two segments pass through their quadrant.
+// And the region cut off by the shortcut may not contain any other vertex, nor may the shortcut cross any other segment.
+// So the snapped rings still don't intersect.
+func simplifyPolygons(ix *pointindex.PointIndex, level pointindex.Level, polygons [][][][2]float64, tolerance float64) [][][][2]float64 {
+	usage := make(map[[2]float64]int)
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			for _, vertex := range ring {
+				usage[vertex]++
+			}
+		}
+	}
+	maxDistance := tolerance * ix.Resolution(level)
+	for _, polygon := range polygons {
+		for ringIdx, ring := range polygon {
+			polygon[ringIdx] = simplifyRing(ix, level, polygons, ring, usage, maxDistance)
+		}
+	}
+	return polygons
+}
+
+// simplifyRing extends a shortcut from the last kept vertex as far as possible, greedily.
+// The ring is one of the polygons, which are checked for vertices and segments in the way of a shortcut.
+func simplifyRing(ix *pointindex.PointIndex, level pointindex.Level, polygons [][][][2]float64, ring [][2]float64,
+	usage map[[2]float64]int, maxDistance float64) [][2]float64 {
+	ringLen := len(ring)
+	if ringLen <= 3 {
+		return ring
+	}
+	simplified := make([][2]float64, 0, ringLen)
+	simplified = append(simplified, ring[0])
+	for anchor := 0; anchor < ringLen; {
+		end := anchor + 1
+		for candidate := anchor + 2; candidate <= ringLen; candidate++ { // the ring's first vertex closes it
+			if !canShortcut(ix, level, polygons, ring, anchor, candidate, usage, maxDistance) {
+				break
+			}
+			end = candidate
+		}
+		if end < ringLen {
+			simplified = append(simplified, ring[end])
+		}
+		anchor = end
+	}
+	if len(simplified) < 3 {
+		return ring
+	}
+	return simplified
+}
+
+// canShortcut returns whether the vertices between from and to can be removed from the ring
+func canShortcut(ix *pointindex.PointIndex, level pointindex.Level, polygons [][][][2]float64, ring [][2]float64, from, to int,
+	usage map[[2]float64]int, maxDistance float64) bool {
+	a, b := ring[from], ring[to%len(ring)]
+	if a == b {
+		return false
+	}
+	skipped := ring[from+1 : to]
+	for _, vertex := range skipped {
+		if usage[vertex] > 1 || distanceToSegment(vertex, a, b) > maxDistance {
+			return false
+		}
+	}
+	for _, point := range ix.IntersectedPoints(geom.Line{a, b}, level) {
+		if point != a && point != b && !slices.Contains(skipped, point) {
+			return false
+		}
+	}
+	return !inTheWay(polygons, ring, from, to)
+}
+
+// inTheWay returns whether a vertex (of any ring) lies in the region cut off by the shortcut from from to to,
+// or whether the shortcut crosses a segment (other than the ones it replaces).
+// Not all of those need to be hot pixels on the shortcut's way, with a tolerance of an internal pixel or more.
+func inTheWay(polygons [][][][2]float64, ring [][2]float64, from, to int) bool {
+	a, b := ring[from], ring[to%len(ring)]
+	skipped := ring[from+1 : to]
+	region := make([][2]float64, 0, len(skipped)+2)
+	region = append(append(append(region, a), skipped...), b)
+	extent := geom.NewExtent(region...)
+	for _, polygon := range polygons {
+		for _, otherRing := range polygon {
+			isRing := len(otherRing) > 0 && &otherRing[0] == &ring[0] // the same backing array
+			for i, vertex := range otherRing {
+				if vertex != a && vertex != b && !slices.Contains(skipped, vertex) && extent.ContainsPoint(vertex) {
+					if contains, _ := geomhelp.RingContains(region, vertex); contains {
+						return true
+					}
+				}
+				if isRing && i >= from && i < to {
+					continue // replaced by the shortcut
+				}
+				if _, crosses := geomhelp.SegmentsCross(a, b, vertex, otherRing[(i+1)%len(otherRing)]); crosses {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func distanceToSegment(pt, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := ((pt[0]-a[0])*dx + (pt[1]-a[1])*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(pt[0]-(a[0]+t*dx), pt[1]-(a[1]+t*dy))
+}
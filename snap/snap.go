@@ -3,15 +3,17 @@ package snap
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"slices"
 	"sort"
+	"sync"
 
 	"github.com/pdok/texel/geomhelp"
 	"github.com/pdok/texel/pointindex"
 
 	"github.com/pdok/texel/mapslicehelp"
+	"github.com/pdok/texel/mathhelp"
 	"github.com/tobshub/go-sortedmap"
 
 	"github.com/go-spatial/geom/winding"
@@ -31,62 +33,318 @@ const (
 type IsOuter = bool
 
 type Config struct {
-	KeepPointsAndLines  bool
-	IgnoreOutsideGrid   bool
+	KeepPointsAndLines bool
+	IgnoreOutsideGrid  bool
+	// ClipOutsideGrid clips polygons that fall (partly) outside the grid to the extent of the top tile matrix
+	// (or of each tile matrix, if they are snapped independently), instead of ignoring them (IgnoreOutsideGrid) or panicking
+	ClipOutsideGrid     bool
 	ReverseWindingOrder bool
+	// InternalPixelResolution is the number of internal pixels (on one axis) a tile pixel is divided into.
+	// Must be a power of two. Defaults to pointindex.VectorTileInternalPixelResolution if 0.
+	InternalPixelResolution uint
+	// InternalPixelResolutions overrides the InternalPixelResolution per tile matrix
+	InternalPixelResolutions map[tms20.TMID]uint
+	// MinOuterAreas is the minimal area (in tile pixels) of an outer ring per tile matrix. Smaller polygons are removed.
+	MinOuterAreas map[tms20.TMID]float64
+	// MinInnerAreas is the minimal area (in tile pixels) of an inner ring per tile matrix. Smaller holes are filled.
+	MinInnerAreas map[tms20.TMID]float64
+	// SimplifyTolerance is the maximal distance (in internal pixels) of the vertices removed by simplifying the snapped rings.
+	// Zero (the default) means no simplification.
+	SimplifyTolerance float64
+	// Stats optionally collects what snapping removed
+	Stats *Stats `json:"-"`
+	// Debug optionally collects the intermediate stages of snapping
+	Debug *Debug `json:"-"`
+	// Logger optionally logs with the context of the polygon (e.g. its fid). slog.Default() if nil.
+	Logger *slog.Logger `json:"-"`
+	// QuadTree optionally tells whether the tile matrix set is a quad tree (see pointindex.IsQuadTree),
+	// so that it needn't be checked for every polygon. Checked per polygon if nil.
+	QuadTree *bool `json:"-"`
+}
+
+func (c Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+// Stats counts the rings removed by snapping, per tile matrix. Safe for concurrent use.
+type Stats struct {
+	mu sync.Mutex
+	// CollapsedRings counts the rings that collapsed to a point or line (or nothing) by snapping
+	CollapsedRings map[tms20.TMID]uint64
+	// RemovedOuters counts the polygons removed because of the MinOuterAreas
+	RemovedOuters map[tms20.TMID]uint64
+	// FilledInners counts the holes filled because of the MinInnerAreas
+	FilledInners map[tms20.TMID]uint64
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		CollapsedRings: make(map[tms20.TMID]uint64),
+		RemovedOuters:  make(map[tms20.TMID]uint64),
+		FilledInners:   make(map[tms20.TMID]uint64),
+	}
+}
+
+func (s *Stats) countCollapsedRing(tmIDs []tms20.TMID) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tmID := range tmIDs {
+		s.CollapsedRings[tmID]++
+	}
+}
+
+func (s *Stats) countRemovedOuter(tmID tms20.TMID) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.RemovedOuters[tmID]++
+}
+
+func (s *Stats) countFilledInner(tmID tms20.TMID) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.FilledInners[tmID]++
+}
+
+// Validate checks whether the (configured) internal pixel resolutions are powers of two,
+// whether the outside grid options don't conflict and whether the minimal areas and tolerance are not negative
+func (c Config) Validate() error {
+	if c.IgnoreOutsideGrid && c.ClipOutsideGrid {
+		return errors.New("ignoring and clipping (polygons) outside the grid can not be combined")
+	}
+	if c.InternalPixelResolution != 0 && !mathhelp.IsPow2(c.InternalPixelResolution) {
+		return fmt.Errorf("internal pixel resolution should be a power of two: %d", c.InternalPixelResolution)
+	}
+	for tmID, resolution := range c.InternalPixelResolutions {
+		if !mathhelp.IsPow2(resolution) {
+			return fmt.Errorf("internal pixel resolution for tile matrix %d should be a power of two: %d", tmID, resolution)
+		}
+	}
+	if c.SimplifyTolerance < 0 {
+		return fmt.Errorf("simplify tolerance should not be negative: %f", c.SimplifyTolerance)
+	}
+	for _, minAreas := range []map[tms20.TMID]float64{c.MinOuterAreas, c.MinInnerAreas} {
+		for tmID, minArea := range minAreas {
+			if minArea < 0 {
+				return fmt.Errorf("minimal area for tile matrix %d should not be negative: %f", tmID, minArea)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Config) isQuadTree(tileMatrixSet tms20.TileMatrixSet) bool {
+	if c.QuadTree != nil {
+		return *c.QuadTree
+	}
+	return pointindex.IsQuadTree(tileMatrixSet) == nil
+}
+
+// InternalPixelResolutionFor returns the internal pixel resolution to use for a tile matrix
+func (c Config) InternalPixelResolutionFor(tmID tms20.TMID) uint {
+	if resolution, ok := c.InternalPixelResolutions[tmID]; ok {
+		return resolution
+	}
+	if c.InternalPixelResolution != 0 {
+		return c.InternalPixelResolution
+	}
+	return pointindex.VectorTileInternalPixelResolution
+}
+
+// DeepestTileMatrix returns the tile matrix with the deepest level (of the internal pixel grid) in the point index.
+// Not necessarily the one with the highest ID, because of the internal pixel resolutions per tile matrix.
+func (c Config) DeepestTileMatrix(tileMatrixSet tms20.TileMatrixSet, tmIDs []tms20.TMID) tms20.TMID {
+	tmIDsByLevels := tileMatrixIDsByLevels(tileMatrixSet, tmIDs, c)
+	return tmIDsByLevels[slices.Max(maps.Keys(tmIDsByLevels))][0]
 }
 
 // SnapPolygon snaps polygons' points to a tile's internal pixel grid
 // and adds points to lines to prevent intersections.
+// For a quad tree tile matrix set all tile matrices are snapped at once using one PointIndex,
+// otherwise each tile matrix is snapped independently on its own grid.
 //
 //nolint:revive
 func SnapPolygon(polygon geom.Polygon, tileMatrixSet tms20.TileMatrixSet, tmIDs []tms20.TMID, config Config) map[tms20.TMID][]geom.Polygon {
-	deepestID := slices.Max(tmIDs)
-	ix, err := pointindex.FromTileMatrixSet(tileMatrixSet, deepestID)
+	newPolygonsPerTileMatrixID, _ := SnapPolygonOutsideGrid(polygon, tileMatrixSet, tmIDs, config)
+	return newPolygonsPerTileMatrixID
+}
+
+// SnapPolygonOutsideGrid snaps like SnapPolygon and also returns whether the polygon falls (partly) outside the grid
+// (of any of the tile matrices), meaning it was clipped (ClipOutsideGrid) or (partly) left out (IgnoreOutsideGrid)
+func SnapPolygonOutsideGrid(polygon geom.Polygon, tileMatrixSet tms20.TileMatrixSet, tmIDs []tms20.TMID, config Config) (map[tms20.TMID][]geom.Polygon, bool) {
+	if !config.isQuadTree(tileMatrixSet) {
+		return snapPolygonPerTileMatrix(polygon, tileMatrixSet, tmIDs, config)
+	}
+	polygon, outside := clipOutsideGrid(polygon, gridExtent(tileMatrixSet, 0, tmIDs, config), config)
+	if polygon == nil {
+		return map[tms20.TMID][]geom.Polygon{}, outside
+	}
+	config.Debug.addInput(polygon)
+	tmIDsByLevels := tileMatrixIDsByLevels(tileMatrixSet, tmIDs, config)
+	deepestTMID := config.DeepestTileMatrix(tileMatrixSet, tmIDs)
+	ix, err := pointindex.FromTileMatrixSet(tileMatrixSet, deepestTMID, config.InternalPixelResolutionFor(deepestTMID))
+	if err != nil {
+		panic(err) // TODO let processing.processPolygonFunc return err
+	}
+
+	newPolygonsPerLevel := insertAndSnap(ix, polygon, tmIDsByLevels, config)
+
+	newPolygonsPerTileMatrixID := make(map[tms20.TMID][]geom.Polygon, len(newPolygonsPerLevel))
+	for _, level := range mapslicehelp.SortedKeys(newPolygonsPerLevel) {
+		newPolygons := newPolygonsPerLevel[level]
+		for _, tmID := range tmIDsByLevels[level] {
+			if newPolygonsForTileMatrix := removeSmallRings(newPolygons, tileMatrixSet, tmID, config); len(newPolygonsForTileMatrix) > 0 {
+				config.Debug.addFinal(tmID, newPolygonsForTileMatrix)
+				newPolygonsPerTileMatrixID[tmID] = newPolygonsForTileMatrix
+			}
+		}
+	}
+
+	return newPolygonsPerTileMatrixID, outside
+}
+
+// clipOutsideGrid returns whether the polygon falls (partly) outside the extent of the grid,
+// and the polygon clipped to it with ClipOutsideGrid (nil if nothing is left)
+func clipOutsideGrid(polygon geom.Polygon, extent geom.Extent, config Config) (geom.Polygon, bool) {
+	if !config.ClipOutsideGrid && !config.IgnoreOutsideGrid {
+		return polygon, false // not needed, it panics when inserted in the PointIndex
+	}
+	for _, ring := range polygon {
+		for _, vertex := range ring {
+			if extent.ContainsPoint(vertex) {
+				continue
+			}
+			if config.ClipOutsideGrid {
+				return geomhelp.ClipPolygon(polygon, extent), true
+			}
+			return polygon, true // ignored when inserted in the PointIndex
+		}
+	}
+	return polygon, false
+}
+
+// gridExtent returns the extent of a tile matrix (the top one for a PointIndex of the whole tile matrix set),
+// with the max edges moved (less than an internal pixel of the tile matrices) inwards,
+// because the PointIndex regards points on those edges as outside the grid
+func gridExtent(tileMatrixSet tms20.TileMatrixSet, matrixTMID tms20.TMID, tmIDs []tms20.TMID, config Config) geom.Extent {
+	bottomLeft, topRight, err := tileMatrixSet.MatrixBoundingBox(matrixTMID)
 	if err != nil {
 		panic(err) // TODO let processing.processPolygonFunc return err
 	}
-	tmIDsByLevels := tileMatrixIDsByLevels(tileMatrixSet, tmIDs)
-	levels := make([]pointindex.Level, 0, len(tmIDsByLevels))
-	for level := range tmIDsByLevels {
-		levels = append(levels, level)
+	margin := math.MaxFloat64
+	for _, tmID := range tmIDs {
+		margin = min(margin, tileMatrixSet.TileMatrices[tmID].CellSize/float64(config.InternalPixelResolutionFor(tmID))/2)
 	}
+	return geom.Extent{bottomLeft.X(), bottomLeft.Y(), topRight.X() - margin, topRight.Y() - margin}
+}
 
-	err = ix.InsertPolygon(polygon)
+// snapPolygonPerTileMatrix snaps a polygon for every tile matrix using a PointIndex of its own,
+// so clipping (ClipOutsideGrid) is to the extent of each tile matrix.
+// Returns whether the polygon falls (partly) outside the grid of any of the tile matrices too.
+func snapPolygonPerTileMatrix(polygon geom.Polygon, tileMatrixSet tms20.TileMatrixSet, tmIDs []tms20.TMID, config Config) (map[tms20.TMID][]geom.Polygon, bool) {
+	config.Debug.addInput(polygon)
+	newPolygonsPerTileMatrixID := make(map[tms20.TMID][]geom.Polygon, len(tmIDs))
+	anyOutside := false
+	for _, tmID := range tmIDs {
+		tmPolygon, outside := clipOutsideGrid(polygon, gridExtent(tileMatrixSet, tmID, []tms20.TMID{tmID}, config), config)
+		anyOutside = anyOutside || outside
+		if tmPolygon == nil {
+			continue
+		}
+		ix, err := pointindex.FromTileMatrix(tileMatrixSet, tmID, config.InternalPixelResolutionFor(tmID))
+		if err != nil {
+			panic(err) // TODO let processing.processPolygonFunc return err
+		}
+		level := ix.DeepestLevel()
+		if newPolygons, ok := insertAndSnap(ix, tmPolygon, map[pointindex.Level][]tms20.TMID{level: {tmID}}, config)[level]; ok {
+			if newPolygons = removeSmallRings(newPolygons, tileMatrixSet, tmID, config); len(newPolygons) > 0 {
+				config.Debug.addFinal(tmID, newPolygons)
+				newPolygonsPerTileMatrixID[tmID] = newPolygons
+			}
+		}
+	}
+	return newPolygonsPerTileMatrixID, anyOutside
+}
+
+// insertAndSnap inserts the polygon in the PointIndex and snaps it on the levels (of the tile matrices)
+func insertAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, tmIDsByLevels map[pointindex.Level][]tms20.TMID, config Config) map[pointindex.Level][]geom.Polygon {
+	err := ix.InsertPolygon(polygon)
 	if err != nil {
 		outsideGridErr := new(pointindex.OutsideGridError)
 		if errors.As(err, outsideGridErr) && config.IgnoreOutsideGrid {
-			log.Println("[WARNING] skipping polygon because: " + err.Error())
-			return make(map[tms20.TMID][]geom.Polygon)
+			var tmIDs []tms20.TMID
+			for _, levelTMIDs := range tmIDsByLevels {
+				tmIDs = append(tmIDs, levelTMIDs...)
+			}
+			slices.Sort(tmIDs)
+			config.logger().Warn("skipping polygon outside the grid", "tmIDs", tmIDs, "error", err)
+			return nil
 		} else {
 			panic(err)
 		}
 	}
+	config.Debug.addPointIndex(ix)
+	return addPointsAndSnap(ix, polygon, tmIDsByLevels, config)
+}
 
-	newPolygonsPerLevel := addPointsAndSnap(ix, polygon, levels, config)
-
-	newPolygonsPerTileMatrixID := make(map[tms20.TMID][]geom.Polygon, len(newPolygonsPerLevel))
-	for level, newPolygons := range newPolygonsPerLevel {
-		newPolygonsPerTileMatrixID[tmIDsByLevels[level]] = newPolygons
+// removeSmallRings removes the (snapped) polygons with an outer ring smaller than the minimal outer area of the tile matrix
+// and fills the holes smaller than the minimal inner area. Points and lines (KeepPointsAndLines) are kept.
+func removeSmallRings(polygons []geom.Polygon, tileMatrixSet tms20.TileMatrixSet, tmID tms20.TMID, config Config) []geom.Polygon {
+	minOuterArea, minInnerArea := config.MinOuterAreas[tmID], config.MinInnerAreas[tmID]
+	if minOuterArea == 0 && minInnerArea == 0 {
+		return polygons
 	}
-
-	return newPolygonsPerTileMatrixID
+	cellSize := tileMatrixSet.TileMatrices[tmID].CellSize
+	pixelArea := cellSize * cellSize
+	kept := make([]geom.Polygon, 0, len(polygons))
+	for _, polygon := range polygons {
+		if len(polygon) == 0 || len(polygon[0]) < 3 {
+			kept = append(kept, polygon)
+			continue
+		}
+		if geomhelp.Shoelace(polygon[0])/pixelArea < minOuterArea {
+			config.Stats.countRemovedOuter(tmID)
+			continue
+		}
+		newPolygon := make(geom.Polygon, 1, len(polygon))
+		newPolygon[0] = polygon[0]
+		for _, inner := range polygon[1:] {
+			if geomhelp.Shoelace(inner)/pixelArea < minInnerArea {
+				config.Stats.countFilledInner(tmID)
+				continue
+			}
+			newPolygon = append(newPolygon, inner)
+		}
+		kept = append(kept, newPolygon)
+	}
+	return kept
 }
 
-func tileMatrixIDsByLevels(tms tms20.TileMatrixSet, tmIDs []tms20.TMID) map[pointindex.Level]tms20.TMID {
-	rootTM := tms.TileMatrices[0]
-	levelDiff := uint(math.Log2(float64(rootTM.TileWidth))) + uint(math.Log2(float64(pointindex.VectorTileInternalPixelResolution)))
-	tmIDsByLevels := make(map[pointindex.Level]tms20.TMID, len(tmIDs))
+// tileMatrixIDsByLevels maps the tile matrices to the levels of the point index.
+// Multiple tile matrices can share a level, if they have different internal pixel resolutions.
+func tileMatrixIDsByLevels(tms tms20.TileMatrixSet, tmIDs []tms20.TMID, config Config) map[pointindex.Level][]tms20.TMID {
+	tmIDsByLevels := make(map[pointindex.Level][]tms20.TMID, len(tmIDs))
 	for _, tmID := range tmIDs {
-		// assuming 2^(tmID) = tm.MatrixWidth = tm.MatrixHeight
-		level := uint(tmID) + levelDiff
-		tmIDsByLevels[level] = tmID
+		level := pointindex.LevelForTileMatrix(tms, tmID, config.InternalPixelResolutionFor(tmID))
+		tmIDsByLevels[level] = append(tmIDsByLevels[level], tmID)
 	}
 	return tmIDsByLevels
 }
 
 //nolint:cyclop
-func addPointsAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, levels []pointindex.Level, config Config) map[pointindex.Level][]geom.Polygon {
+func addPointsAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, tmIDsByLevels map[pointindex.Level][]tms20.TMID, config Config) map[pointindex.Level][]geom.Polygon {
+	levels := maps.Keys(tmIDsByLevels)
 	levelMap := mapslicehelp.AsKeys(levels)
 	newOuters := make(map[pointindex.Level][][][2]float64, len(levels))
 	newInners := make(map[pointindex.Level][][][2]float64, len(levels))
@@ -101,8 +359,9 @@ func addPointsAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, levels []
 		// winding order is reversed if incorrect
 		ring = ensureCorrectWindingOrder(ring, !isOuter)
 		ringLen := len(ring)
+		ringLevels := mapslicehelp.SortedKeys(levelMap) // levels are iterated in order, for reproducible results
 		newRing := make(map[pointindex.Level][][2]float64, len(levelMap))
-		for level := range levelMap {
+		for _, level := range ringLevels {
 			newRing[level] = make([][2]float64, 0, 2*ringLen) // TODO better estimation of new amount of points for a ring
 		}
 
@@ -113,15 +372,19 @@ func addPointsAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, levels []
 			nextVertexIdx := (vertexIdx + 1) % ringLen
 			segment := geom.Line{vertex, ring[nextVertexIdx]}
 			newVertices := ix.SnapClosestPoints(segment, levelMap, ringIdx)
-			for level := range levelMap {
+			for _, level := range ringLevels {
 				cleanedNewVertices := cleanupNewVertices(newVertices[level], segment, level, mapslicehelp.LastElement(newRing[level]))
 				newRing[level] = append(newRing[level], cleanedNewVertices...)
 			}
 		}
 
 		// walk through the new ring and append to the polygon (on all levels)
-		for level := range levelMap {
-			outerRings, innerRings, pointsAndLines := cleanupNewRing(newRing[level], isOuter, ix.GetHitMultiple(level), ringIdx)
+		for _, level := range ringLevels {
+			config.Debug.addRing("raw ring", level, ringIdx, newRing[level])
+			outerRings, innerRings, pointsAndLines := cleanupNewRing(newRing[level], isOuter, ix.GetHitMultiple(level), ringIdx, level, config.Debug)
+			if (isOuter && len(outerRings) == 0) || (!isOuter && len(innerRings) == 0) {
+				config.Stats.countCollapsedRing(tmIDsByLevels[level])
+			}
 			// Check if outer ring has become too small
 			if isOuter && len(outerRings) == 0 && (!config.KeepPointsAndLines || len(pointsAndLines) == 0) {
 				delete(levelMap, level) // If too small, delete it
@@ -136,9 +399,17 @@ func addPointsAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, levels []
 	}
 
 	newPolygons := make(map[pointindex.Level][][][][2]float64, len(levels))
-	for l := range levelMap {
+	for _, l := range mapslicehelp.SortedKeys(levelMap) {
 		newOuters[l], newInners[l] = dedupeInnersOuters(newOuters[l], newInners[l])
-		newPolygonsForLevel := matchInnersToPolygons(outersToPolygons(newOuters[l]), newInners[l], len(polygon) > 1)
+		config.Debug.addOutersInners("deduplicated", l, newOuters[l], newInners[l])
+		newPolygonsForLevel, innersTurnedOuters := matchInnersToPolygons(outersToPolygons(newOuters[l]), newInners[l])
+		if innersTurnedOuters > 0 {
+			config.logger().Debug("no matching outer for inner ring found, turned inner into outer",
+				"tmIDs", tmIDsByLevels[l], "count", innersTurnedOuters, "originalHasInners", len(polygon) > 1)
+		}
+		if config.SimplifyTolerance > 0 {
+			newPolygonsForLevel = simplifyPolygons(ix, l, newPolygonsForLevel, config.SimplifyTolerance)
+		}
 		reverseWindingOrderIfConfigured(newPolygonsForLevel, config)
 		if len(newPolygonsForLevel) > 0 {
 			newPolygons[l] = newPolygonsForLevel
@@ -146,8 +417,8 @@ func addPointsAndSnap(ix *pointindex.PointIndex, polygon geom.Polygon, levels []
 	}
 
 	// points and lines at the end, as outer rings
-	for level, pointsAndLines := range newPointsAndLines {
-		for _, pointOrLine := range pointsAndLines {
+	for _, level := range mapslicehelp.SortedKeys(newPointsAndLines) {
+		for _, pointOrLine := range newPointsAndLines[level] {
 			newPolygons[level] = append(newPolygons[level], [][][2]float64{pointOrLine})
 		}
 	}
@@ -274,10 +545,12 @@ func ringsAreEqual(ringI, ringJ [][2]float64, iIsOuter, jIsOuter bool) bool {
 	return true
 }
 
-func matchInnersToPolygons(polygons [][][][2]float64, innerRings [][][2]float64, hasInners bool) [][][][2]float64 {
+// matchInnersToPolygons adds the inner rings to the polygons with a matching outer ring,
+// returning the number of inner rings without a match that were turned into outer rings too
+func matchInnersToPolygons(polygons [][][][2]float64, innerRings [][][2]float64) ([][][][2]float64, int) {
 	lenPolygons := len(polygons)
 	if len(innerRings) == 0 {
-		return polygons
+		return polygons, 0
 	}
 
 	var polyISortedByOuterAreaDesc []int
@@ -305,7 +578,6 @@ matchInners:
 			// presumably because the inner ring's winding order is incorrect and it should have been an outer
 			// TODO is that presumption correct and is this really never a panic? // panicNoMatchingOuterForInnerRing(polygons, innerRing)
 			// TODO should it be a candidate for other the other inner rings?
-			log.Printf("no matching outer for inner ring found, turned inner into outer. original has inners: %v", hasInners)
 			innersTurnedOuters = append(innersTurnedOuters, mapslicehelp.ReverseClone(innerRing))
 			continue
 		}
@@ -320,7 +592,7 @@ matchInners:
 	for i := range innersTurnedOuters {
 		polygons = append(polygons, [][][2]float64{innersTurnedOuters[i]})
 	}
-	return polygons
+	return polygons, len(innersTurnedOuters)
 }
 
 func sortPolyIdxsByOuterAreaDesc(polygons [][][][2]float64) []int {
@@ -337,29 +609,10 @@ func sortPolyIdxsByOuterAreaDesc(polygons [][][][2]float64) []int {
 	return areas.Keys()
 }
 
-// from paulmach/orb, modified to also return whether it's on the boundary
 // ringContains returns true if the point is inside the ring.
 // Points on the boundary are also considered in. In which case the second returned var is true too.
 func ringContains(ring [][2]float64, point [2]float64) (contains, onBoundary bool) {
-	// TODO check first if the point is in the extent/bound/envelop
-
-	c, on := geomhelp.RayIntersect(point, ring[0], ring[len(ring)-1])
-	if on {
-		return true, true
-	}
-
-	for i := 0; i < len(ring)-1; i++ {
-		intersects, on := geomhelp.RayIntersect(point, ring[i], ring[i+1])
-		if on {
-			return true, true
-		}
-
-		if intersects {
-			c = !c // https://en.wikipedia.org/wiki/Even-odd_rule
-		}
-	}
-
-	return c, false
+	return geomhelp.RingContains(ring, point)
 }
 
 // cleanupNewVertices cleans up the closest points for a line that were just retrieved inside addPointsAndSnap
@@ -380,7 +633,7 @@ func cleanupNewVertices(newVertices [][2]float64, segment [2][2]float64, level p
 }
 
 // cleanupNewRing cleans up a ring (if not too small) that was just crafted inside addPointsAndSnap
-func cleanupNewRing(newRing [][2]float64, isOuter bool, hitMultiple map[intgeom.Point][]int, ringIdx int) (outerRings, innerRings, pointsAndLines [][][2]float64) {
+func cleanupNewRing(newRing [][2]float64, isOuter bool, hitMultiple map[intgeom.Point][]int, ringIdx int, level pointindex.Level, debug *Debug) (outerRings, innerRings, pointsAndLines [][][2]float64) {
 	newRingLen := len(newRing)
 	// LinearRings(): "The last point in the linear ring will not match the first point."
 	if newRingLen > 1 && newRing[0] == newRing[newRingLen-1] {
@@ -393,13 +646,18 @@ func cleanupNewRing(newRing [][2]float64, isOuter bool, hitMultiple map[intgeom.
 	}
 	// deduplicate points in the ring
 	newRing = kmpDeduplicate(newRing)
+	debug.addRing("kmp deduplicated", level, ringIdx, newRing)
 	newRingLen = len(newRing)
 	// again filter out too small rings, after deduping
 	if newRingLen < 3 {
 		return nil, nil, [][][2]float64{newRing}
 	}
 	// split ring and return results
-	return splitRing(newRing, isOuter, hitMultiple, ringIdx)
+	outerRings, innerRings, pointsAndLines = splitRing(newRing, isOuter, hitMultiple, ringIdx)
+	debug.addRings("split outer rings", level, ringIdx, outerRings)
+	debug.addRings("split inner rings", level, ringIdx, innerRings)
+	debug.addRings("split points and lines", level, ringIdx, pointsAndLines)
+	return outerRings, innerRings, pointsAndLines
 }
 
 // if winding order is incorrect, ring is reversed to correct winding order
@@ -1,10 +1,16 @@
 package snap
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/pdok/texel/geomhelp"
+	"github.com/pdok/texel/mapslicehelp"
 	"github.com/pdok/texel/mathhelp"
 	"github.com/pdok/texel/pointindex"
 	"github.com/stretchr/testify/require"
@@ -715,6 +721,50 @@ func TestSnap_snapPolygon(t *testing.T) {
 			polygon: geom.Polygon{{{0.1, 0.1}, {0.2, 0.1}, {0.2, -0.1}}},
 			want:    map[tms20.TMID][]geom.Polygon{}, // empty, ignored
 		},
+		{
+			name:    "clip outside grid",
+			tms:     newSimpleTileMatrixSet(2, 64),
+			config:  Config{ClipOutsideGrid: true},
+			tmIDs:   []tms20.TMID{1},
+			polygon: geom.Polygon{{{-60.0, -60.0}, {60.0, -60.0}, {60.0, 60.0}, {-60.0, 60.0}}},
+			want: map[tms20.TMID][]geom.Polygon{
+				1: {{{{4.0, 4.0}, {60.0, 4.0}, {60.0, 60.0}, {4.0, 60.0}}}},
+			},
+		},
+		{
+			name:   "clip outside grid, max edges and inner ring",
+			tms:    newSimpleTileMatrixSet(2, 64),
+			config: Config{ClipOutsideGrid: true},
+			tmIDs:  []tms20.TMID{1},
+			polygon: geom.Polygon{
+				{{188.0, 188.0}, {188.0, 300.0}, {300.0, 300.0}, {300.0, 188.0}},
+				{{270.0, 270.0}, {290.0, 270.0}, {290.0, 290.0}, {270.0, 290.0}}, // outside the grid
+			},
+			want: map[tms20.TMID][]geom.Polygon{
+				1: {{{{188.0, 252.0}, {188.0, 188.0}, {252.0, 188.0}, {252.0, 252.0}}}},
+			},
+		},
+		{
+			name:    "clip outside grid, concave",
+			tms:     newSimpleTileMatrixSet(3, 16),
+			config:  Config{ClipOutsideGrid: true},
+			tmIDs:   []tms20.TMID{3},
+			polygon: geom.Polygon{{{10, 50}, {10, -20}, {50, -20}, {50, 50}, {40, 50}, {40, -10}, {20, -10}, {20, 50}}},
+			want: map[tms20.TMID][]geom.Polygon{
+				3: {
+					{{{10.5, 50.5}, {10.5, 0.5}, {20.5, 0.5}, {20.5, 50.5}}}, // the degenerate edges along the grid's edge are removed
+					{{{40.5, 0.5}, {50.5, 0.5}, {50.5, 50.5}, {40.5, 50.5}}},
+				},
+			},
+		},
+		{
+			name:    "clip outside grid, completely",
+			tms:     newSimpleTileMatrixSet(2, 64),
+			config:  Config{ClipOutsideGrid: true},
+			tmIDs:   []tms20.TMID{1},
+			polygon: geom.Polygon{{{-60.0, -60.0}, {-4.0, -60.0}, {-4.0, -4.0}, {-60.0, -4.0}}},
+			want:    map[tms20.TMID][]geom.Polygon{},
+		},
 		{
 			name:   "correct winding order",
 			tms:    newSimpleTileMatrixSet(2, 64),
@@ -779,6 +829,57 @@ func TestSnap_snapPolygon(t *testing.T) {
 				}},
 			},
 		},
+		{
+			name:  "not a quad tree, tile matrices snapped independently",
+			tms:   loadEmbeddedTileMatrixSet(t, "GNOSISGlobalGrid"),
+			tmIDs: []tms20.TMID{1, 2},
+			polygon: geom.Polygon{
+				{{0.003, 0.003}, {0.05, 0.003}, {0.05, 0.05}, {0.003, 0.05}},
+			},
+			// slightly off the exact centroids (e.g. 0.0054931640625), because of the int precision (see pointindex.DeviationStats)
+			want: map[tms20.TMID][]geom.Polygon{
+				1: {{
+					{{0.0054927544, 0.0054933688}, {0.0494380668, 0.0054933688}, {0.0494380668, 0.0494386812}, {0.0054927544, 0.0494386812}},
+				}},
+				2: {{
+					{{0.002744534, 0.002747606}, {0.05218301, 0.002747606}, {0.05218301, 0.052186082}, {0.002744534, 0.052186082}},
+				}},
+			},
+		},
+		{
+			name:   "lower internal pixel resolution",
+			tms:    newSimpleTileMatrixSet(1, 8),
+			tmIDs:  []tms20.TMID{1},
+			config: Config{InternalPixelResolution: 2},
+			polygon: geom.Polygon{
+				{{3.0, 3.0}, {13.0, 3.0}, {13.0, 13.0}, {3.0, 13.0}},
+			},
+			want: map[tms20.TMID][]geom.Polygon{
+				1: {{
+					{{2.0, 2.0}, {14.0, 2.0}, {14.0, 14.0}, {2.0, 14.0}},
+				}},
+			},
+		},
+		{
+			name:  "internal pixel resolution per tile matrix, sharing a level",
+			tms:   newSimpleTileMatrixSet(1, 8),
+			tmIDs: []tms20.TMID{0, 1},
+			config: Config{InternalPixelResolutions: map[tms20.TMID]uint{
+				0: 4,
+				1: 2,
+			}},
+			polygon: geom.Polygon{
+				{{3.0, 3.0}, {13.0, 3.0}, {13.0, 13.0}, {3.0, 13.0}},
+			},
+			want: map[tms20.TMID][]geom.Polygon{
+				0: {{
+					{{2.0, 2.0}, {14.0, 2.0}, {14.0, 14.0}, {2.0, 14.0}},
+				}},
+				1: {{
+					{{2.0, 2.0}, {14.0, 2.0}, {14.0, 14.0}, {2.0, 14.0}},
+				}},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -799,6 +900,314 @@ func TestSnap_snapPolygon(t *testing.T) {
 	}
 }
 
+func TestSnap_tileMatrixIDsByLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		tms    tms20.TileMatrixSet
+		tmIDs  []tms20.TMID
+		config Config
+		want   map[pointindex.Level][]tms20.TMID
+	}{
+		{
+			name:  "default resolution",
+			tms:   loadEmbeddedTileMatrixSet(t, "NetherlandsRDNewQuad"),
+			tmIDs: []tms20.TMID{5, 6, 7},
+			want:  map[pointindex.Level][]tms20.TMID{17: {5}, 18: {6}, 19: {7}},
+		},
+		{
+			name:   "resolution for the run",
+			tms:    loadEmbeddedTileMatrixSet(t, "NetherlandsRDNewQuad"),
+			tmIDs:  []tms20.TMID{5, 6, 7},
+			config: Config{InternalPixelResolution: 1},
+			want:   map[pointindex.Level][]tms20.TMID{13: {5}, 14: {6}, 15: {7}},
+		},
+		{
+			name:   "resolution per tile matrix",
+			tms:    loadEmbeddedTileMatrixSet(t, "NetherlandsRDNewQuad"),
+			tmIDs:  []tms20.TMID{5, 6, 7},
+			config: Config{InternalPixelResolutions: map[tms20.TMID]uint{6: 32}},
+			want:   map[pointindex.Level][]tms20.TMID{17: {5}, 19: {6, 7}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tileMatrixIDsByLevels(tt.tms, tt.tmIDs, tt.config))
+		})
+	}
+}
+
+func TestConfig_DeepestTileMatrix(t *testing.T) {
+	tms := loadEmbeddedTileMatrixSet(t, "NetherlandsRDNewQuad")
+	tests := []struct {
+		name   string
+		config Config
+		want   tms20.TMID
+	}{
+		{name: "default resolution", want: 7},
+		{name: "sharing the deepest level", config: Config{InternalPixelResolutions: map[tms20.TMID]uint{6: 32}}, want: 6},
+		{name: "deeper than the highest ID", config: Config{InternalPixelResolutions: map[tms20.TMID]uint{5: 256}}, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.DeepestTileMatrix(tms, []tms20.TMID{5, 6, 7}))
+		})
+	}
+}
+
+func TestSnap_minAreas(t *testing.T) {
+	tms := newSimpleTileMatrixSet(2, 64) // a tile pixel of tile matrix 1 is 128 x 128
+	polygon := geom.Polygon{
+		{{4.0, 124.0}, {4.0, 4.0}, {60.0, 4.0}, {60.0, 124.0}},                   // 0.41 tile pixel
+		{{12.0, 116.0}, {52.0, 116.0}, {52.0, 76.0}, {28.0, 76.0}, {12.0, 76.0}}, // 0.098 tile pixel
+	}
+	tests := []struct {
+		name              string
+		config            Config
+		want              map[tms20.TMID][]geom.Polygon
+		wantRemovedOuters map[tms20.TMID]uint64
+		wantFilledInners  map[tms20.TMID]uint64
+	}{
+		{
+			name:   "no minimal areas",
+			config: Config{},
+			want: map[tms20.TMID][]geom.Polygon{1: {{
+				{{4.0, 124.0}, {4.0, 4.0}, {60.0, 4.0}, {60.0, 124.0}},
+				{{12.0, 116.0}, {52.0, 116.0}, {52.0, 76.0}, {28.0, 76.0}, {12.0, 76.0}},
+			}}},
+			wantRemovedOuters: map[tms20.TMID]uint64{},
+			wantFilledInners:  map[tms20.TMID]uint64{},
+		},
+		{
+			name:   "small hole filled",
+			config: Config{MinOuterAreas: map[tms20.TMID]float64{1: 0.4}, MinInnerAreas: map[tms20.TMID]float64{1: 0.1}},
+			want: map[tms20.TMID][]geom.Polygon{1: {{
+				{{4.0, 124.0}, {4.0, 4.0}, {60.0, 4.0}, {60.0, 124.0}},
+			}}},
+			wantRemovedOuters: map[tms20.TMID]uint64{},
+			wantFilledInners:  map[tms20.TMID]uint64{1: 1},
+		},
+		{
+			name:              "small polygon removed",
+			config:            Config{MinOuterAreas: map[tms20.TMID]float64{1: 0.5}},
+			want:              map[tms20.TMID][]geom.Polygon{},
+			wantRemovedOuters: map[tms20.TMID]uint64{1: 1},
+			wantFilledInners:  map[tms20.TMID]uint64{},
+		},
+		{
+			name:   "other tile matrix",
+			config: Config{MinOuterAreas: map[tms20.TMID]float64{2: 0.5}},
+			want: map[tms20.TMID][]geom.Polygon{1: {{
+				{{4.0, 124.0}, {4.0, 4.0}, {60.0, 4.0}, {60.0, 124.0}},
+				{{12.0, 116.0}, {52.0, 116.0}, {52.0, 76.0}, {28.0, 76.0}, {12.0, 76.0}},
+			}}},
+			wantRemovedOuters: map[tms20.TMID]uint64{},
+			wantFilledInners:  map[tms20.TMID]uint64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Stats = NewStats()
+			got := SnapPolygon(polygon, tms, []tms20.TMID{1}, tt.config)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRemovedOuters, tt.config.Stats.RemovedOuters)
+			assert.Equal(t, tt.wantFilledInners, tt.config.Stats.FilledInners)
+		})
+	}
+}
+
+func TestSnap_simplify(t *testing.T) {
+	tms := newSimpleTileMatrixSet(2, 64) // an internal pixel of tile matrix 2 is 4 x 4
+	outer := [][2]float64{{2, 14}, {102, 2}, {202, 14}, {202, 202}, {2, 202}}
+	tests := []struct {
+		name      string
+		polygon   geom.Polygon
+		tolerance float64
+		want      map[tms20.TMID][]geom.Polygon
+	}{
+		{
+			name:      "no simplification",
+			polygon:   geom.Polygon{outer},
+			tolerance: 0,
+			want:      map[tms20.TMID][]geom.Polygon{2: {{outer}}},
+		},
+		{
+			name:      "vertex further than the tolerance",
+			polygon:   geom.Polygon{outer},
+			tolerance: 2,
+			want:      map[tms20.TMID][]geom.Polygon{2: {{outer}}},
+		},
+		{
+			name:      "vertex within the tolerance",
+			polygon:   geom.Polygon{outer},
+			tolerance: 4,
+			want:      map[tms20.TMID][]geom.Polygon{2: {{{{2, 14}, {202, 14}, {202, 202}, {2, 202}}}}},
+		},
+		{
+			name:      "shortcut would touch a point of the inner ring",
+			polygon:   geom.Polygon{outer, {{102, 14}, {110, 30}, {94, 30}}},
+			tolerance: 4,
+			want:      map[tms20.TMID][]geom.Polygon{2: {{outer, {{94, 30}, {110, 30}, {102, 14}}}}},
+		},
+		{
+			name: "shortcut would cut off a vertex of the inner ring",
+			polygon: geom.Polygon{{{2, 2}, {202, 2}, {202, 202}, {102, 214}, {2, 202}},
+				{{102, 210}, {90, 150}, {114, 150}}},
+			tolerance: 4,
+			want: map[tms20.TMID][]geom.Polygon{2: {{{{2, 2}, {202, 2}, {202, 202}, {102, 214}, {2, 202}},
+				{{114, 150}, {90, 150}, {102, 210}}}}},
+		},
+		{
+			name:      "vertex shared with the inner ring",
+			polygon:   geom.Polygon{outer, {{102, 2}, {110, 30}, {94, 30}}},
+			tolerance: 4,
+			want:      map[tms20.TMID][]geom.Polygon{2: {{outer, {{94, 30}, {110, 30}, {102, 2}}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SnapPolygon(tt.polygon, tms, []tms20.TMID{2}, Config{SimplifyTolerance: tt.tolerance})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSnap_collapsedRings(t *testing.T) {
+	tms := newSimpleTileMatrixSet(2, 1) // an internal pixel of tile matrix 0 is 0.25 x 0.25, of 2 0.0625 x 0.0625
+	polygon := geom.Polygon{
+		{{0.0, 0.0}, {3.0, 0.0}, {3.0, 3.0}, {0.0, 3.0}},
+		{{1.02, 1.02}, {1.02, 1.22}, {1.22, 1.22}, {1.22, 1.02}},
+	}
+	stats := NewStats()
+	got := SnapPolygon(polygon, tms, []tms20.TMID{0, 1, 2}, Config{Stats: stats})
+	assert.Len(t, got[0], 1)
+	assert.Len(t, got[0][0], 1, "hole collapsed")
+	assert.Len(t, got[2][0], 2, "hole kept")
+	assert.Equal(t, map[tms20.TMID]uint64{0: 1}, stats.CollapsedRings)
+}
+
+func TestSnapPolygonOutsideGrid_perTileMatrix(t *testing.T) {
+	tms := loadEmbeddedTileMatrixSet(t, "CanadianNAD83_LCC") // not a quad tree, the matrices cover different extents
+	_, topRight3, err := tms.MatrixBoundingBox(3)
+	require.NoError(t, err)
+	polygon := geom.Polygon{{{7.9e6, 0.0}, {8.5e6, 0.0}, {8.5e6, 1e6}, {7.9e6, 1e6}}} // within tile matrix 0, not 3
+
+	got, outside := SnapPolygonOutsideGrid(polygon, tms, []tms20.TMID{0, 3}, Config{ClipOutsideGrid: true})
+	assert.True(t, outside)
+	require.Len(t, got[0], 1)
+	require.Len(t, got[3], 1)
+	assert.Greater(t, maxX(got[0][0]), topRight3.X(), "not clipped for tile matrix 0")
+	assert.LessOrEqual(t, maxX(got[3][0]), topRight3.X(), "clipped to tile matrix 3")
+
+	got, outside = SnapPolygonOutsideGrid(polygon, tms, []tms20.TMID{0, 3}, Config{IgnoreOutsideGrid: true})
+	assert.True(t, outside)
+	assert.Len(t, got[0], 1)
+	assert.NotContains(t, got, 3, "ignored for tile matrix 3")
+
+	_, outside = SnapPolygonOutsideGrid(polygon, tms, []tms20.TMID{0}, Config{ClipOutsideGrid: true})
+	assert.False(t, outside)
+}
+
+func maxX(polygon geom.Polygon) float64 {
+	x := math.Inf(-1)
+	for _, ring := range polygon {
+		for _, vertex := range ring {
+			x = max(x, vertex[0])
+		}
+	}
+	return x
+}
+
+func TestSnap_debug(t *testing.T) {
+	tms := newSimpleTileMatrixSet(2, 64)
+	polygon := geom.Polygon{
+		{{4.0, 124.0}, {4.0, 4.0}, {60.0, 4.0}, {60.0, 124.0}},
+		{{12.0, 116.0}, {52.0, 116.0}, {52.0, 76.0}, {12.0, 76.0}},
+	}
+	debug := &Debug{}
+	got := SnapPolygon(polygon, tms, []tms20.TMID{1}, Config{Debug: debug})
+
+	var names []string
+	for _, stage := range debug.Stages {
+		names = append(names, stage.Name)
+	}
+	assert.Equal(t, []string{
+		"input",
+		"point index",
+		"raw ring level 5 ring 0",
+		"kmp deduplicated level 5 ring 0",
+		"split outer rings level 5 ring 0",
+		"raw ring level 5 ring 1",
+		"kmp deduplicated level 5 ring 1",
+		"split inner rings level 5 ring 1",
+		"deduplicated outers level 5",
+		"deduplicated inners level 5",
+		"final tile matrix 1",
+	}, names)
+	final := debug.Stages[len(debug.Stages)-1]
+	assert.Equal(t, geom.MultiPolygon{got[1][0]}, final.Geometry)
+}
+
+func TestSnap_reproducible(t *testing.T) {
+	tms := newSimpleTileMatrixSet(4, 1)
+	tmIDs := []tms20.TMID{0, 1, 2, 3, 4}
+	config := Config{
+		KeepPointsAndLines:       true,
+		InternalPixelResolutions: map[tms20.TMID]uint{4: 8}, // shares a level with tile matrix 3
+		SimplifyTolerance:        1,
+	}
+	reversedTMIDs := slices.Clone(tmIDs)
+	slices.Reverse(reversedTMIDs)
+	for i, polygon := range newCorpus(200, 16) {
+		first := snapToString(polygon, tms, tmIDs, config)
+		second := snapToString(polygon, tms, reversedTMIDs, config)
+		require.Equal(t, first, second, "polygon %d: %v", i, polygon)
+	}
+}
+
+// snapToString snaps a polygon and prints the results in the order of the tile matrices
+func snapToString(polygon geom.Polygon, tms tms20.TileMatrixSet, tmIDs []tms20.TMID, config Config) string {
+	newPolygonsPerTileMatrix := SnapPolygon(polygon, tms, tmIDs, config)
+	var sb strings.Builder
+	for _, tmID := range mapslicehelp.SortedKeys(newPolygonsPerTileMatrix) {
+		fmt.Fprintf(&sb, "%d: %v\n", tmID, newPolygonsPerTileMatrix[tmID])
+	}
+	return sb.String()
+}
+
+// newCorpus generates (the same) random star shaped polygons, half of them with a hole, within the extent (0, 0, size, size)
+func newCorpus(count int, size float64) []geom.Polygon {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	starRing := func(center [2]float64, minRadius, maxRadius float64, vertices int) [][2]float64 {
+		ring := make([][2]float64, vertices)
+		for i := range ring {
+			angle := 2 * math.Pi * (float64(i) + rnd.Float64()*0.9) / float64(vertices)
+			radius := minRadius + rnd.Float64()*(maxRadius-minRadius)
+			ring[i] = [2]float64{center[0] + radius*math.Cos(angle), center[1] + radius*math.Sin(angle)}
+		}
+		return ring
+	}
+	corpus := make([]geom.Polygon, count)
+	for i := range corpus {
+		radius := 0.05*size + rnd.Float64()*0.2*size
+		center := [2]float64{radius + rnd.Float64()*(size-2*radius), radius + rnd.Float64()*(size-2*radius)}
+		corpus[i] = geom.Polygon{starRing(center, radius/2, radius, 3+rnd.Intn(60))}
+		if rnd.Intn(2) == 0 {
+			corpus[i] = append(corpus[i], starRing(center, radius/8, radius/4, 3+rnd.Intn(10)))
+		}
+	}
+	return corpus
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{InternalPixelResolution: 1, InternalPixelResolutions: map[tms20.TMID]uint{3: 64}}.Validate())
+	assert.Error(t, Config{InternalPixelResolution: 12}.Validate())
+	assert.Error(t, Config{InternalPixelResolutions: map[tms20.TMID]uint{3: 0}}.Validate())
+	assert.Error(t, Config{IgnoreOutsideGrid: true, ClipOutsideGrid: true}.Validate())
+	assert.Error(t, Config{MinInnerAreas: map[tms20.TMID]float64{3: -1}}.Validate())
+	assert.Error(t, Config{SimplifyTolerance: -1}.Validate())
+}
+
 func TestSnap_ringContains(t *testing.T) {
 	type args struct {
 		ring  [][2]float64
@@ -1012,8 +1421,8 @@ func newSimpleTileMatrixSet(deepestTMID pointindex.Level, cellSize float64) tms2
 		TileMatrices: make(map[tms20.TMID]tms20.TileMatrix, deepestTMID+1),
 	}
 	for tmID := 0; tmID <= int(deepestTMID); tmID++ {
-		// (only values from the root tm are used, for the rest it is assumed to follow quad matrix rules)
 		tmCellSize := cellSize * float64(mathhelp.Pow2(deepestTMID-uint(tmID)))
+		matrixSize := mathhelp.Pow2(uint(tmID))
 		tms.TileMatrices[tmID] = tms20.TileMatrix{
 			ID:               strconv.Itoa(tmID),
 			ScaleDenominator: tmCellSize / tms20.StandardizedRenderingPixelSize,
@@ -1022,8 +1431,8 @@ func newSimpleTileMatrixSet(deepestTMID pointindex.Level, cellSize float64) tms2
 			PointOfOrigin:    &zeroZero,
 			TileWidth:        1,
 			TileHeight:       1,
-			MatrixWidth:      1,
-			MatrixHeight:     1,
+			MatrixWidth:      matrixSize,
+			MatrixHeight:     matrixSize,
 		}
 	}
 	return tms
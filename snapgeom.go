@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/geojson"
+	"github.com/go-spatial/geom/encoding/wkt"
+	"github.com/pdok/texel/geomhelp"
+	"github.com/pdok/texel/pointindex"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+	"github.com/urfave/cli/v2"
+)
+
+const FORMAT string = `format`
+
+const (
+	formatWKT     = "wkt"
+	formatGeoJSON = "geojson"
+)
+
+//nolint:funlen
+func snapGeomCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "snap-geom",
+		Usage:     "Snap a single (MULTI)POLYGON, given as WKT or GeoJSON geometry, and print the result per tile matrix",
+		ArgsUsage: "[geometry, read from stdin if left out]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     TILEMATRIXSET,
+				Aliases:  []string{"tms"},
+				Usage:    `ID of a (built-in) tile matrix set or a tile matrix set JSON file. E.g.: NetherlandsRDNewQuad`,
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     TILEMATRICES,
+				Aliases:  []string{"z"},
+				Usage:    `IDs of the tile matrices to snap for. JSON array of integers or a range by scale denominator or resolution. E.g.: [5,6,7]`,
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:    KEEPPOINTSANDLINES,
+				Aliases: []string{"k"},
+				Usage:   "to keep points and lines in the output",
+			},
+			&cli.BoolFlag{
+				Name:    CLIPOUTSIDEGRID,
+				Aliases: []string{"cog"},
+				Usage:   "to clip the geometry to the extent of the tile matrix set, if it falls (partly) outside",
+			},
+			&cli.UintFlag{
+				Name:    INTERNALPIXELRESOLUTION,
+				Aliases: []string{"ipr"},
+				Usage:   "Number of internal pixels (on one axis) a tile pixel is divided into. Must be a power of two",
+				Value:   pointindex.VectorTileInternalPixelResolution,
+			},
+			&cli.Float64Flag{
+				Name:    SIMPLIFY,
+				Aliases: []string{"simp"},
+				Usage:   "Simplify the snapped rings, removing vertices within this distance (in internal pixels). 0 is no simplification",
+			},
+			&cli.StringFlag{
+				Name:    FORMAT,
+				Aliases: []string{"f"},
+				Usage:   `Output format, "wkt" or "geojson" (a feature collection with a feature per tile matrix)`,
+				Value:   formatWKT,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String(FORMAT)
+			if format != formatWKT && format != formatGeoJSON {
+				return fmt.Errorf(`unknown format "%s", should be "%s" or "%s"`, format, formatWKT, formatGeoJSON)
+			}
+			polygons, err := readGeometryArg(c)
+			if err != nil {
+				return err
+			}
+			tms, err := tms20.LoadTileMatrixSet(c.String(TILEMATRIXSET))
+			if err != nil {
+				return err
+			}
+			tmIDs, auto, err := parseTileMatrices(c.String(TILEMATRICES), tms)
+			if err != nil {
+				return err
+			}
+			if auto {
+				return fmt.Errorf("%s can't be %s for a single geometry", TILEMATRICES, autoTileMatrices)
+			}
+			snapConfig := snap.Config{
+				KeepPointsAndLines:      c.Bool(KEEPPOINTSANDLINES),
+				ClipOutsideGrid:         c.Bool(CLIPOUTSIDEGRID),
+				InternalPixelResolution: c.Uint(INTERNALPIXELRESOLUTION),
+				SimplifyTolerance:       c.Float64(SIMPLIFY),
+				Stats:                   snap.NewStats(),
+			}
+			if err = snapConfig.Validate(); err != nil {
+				return err
+			}
+			if err = validateTileMatrixSet(tms, tmIDs, snapConfig); err != nil {
+				return err
+			}
+			deviation, err := newDeviationReport(tms, tmIDs, snapConfig)
+			if err != nil {
+				return err
+			}
+			slog.Info("deviation", "tms", tms.ID, "tmID", deviation.TileMatrixID, "internalPixelResolution", deviation.InternalPixelResolution,
+				"inUnits", deviation.InUnits, "inPixels", deviation.InPixels)
+
+			newPolygonsPerTileMatrix := make(map[tms20.TMID][]geom.Polygon, len(tmIDs))
+			for _, polygon := range polygons {
+				for tmID, newPolygons := range snap.SnapPolygon(polygon, tms, tmIDs, snapConfig) {
+					newPolygonsPerTileMatrix[tmID] = append(newPolygonsPerTileMatrix[tmID], newPolygons...)
+				}
+			}
+			slog.Info("input", countRings(polygons)...)
+			slices.Sort(tmIDs)
+			features := make([]geojson.Feature, 0, len(tmIDs))
+			for _, tmID := range tmIDs {
+				newPolygons := newPolygonsPerTileMatrix[tmID]
+				level := pointindex.LevelForTileMatrix(tms, tmID, snapConfig.InternalPixelResolutionFor(tmID))
+				counts := append(countRings(newPolygons), "collapsedRings", snapConfig.Stats.CollapsedRings[tmID],
+					"removedOuters", snapConfig.Stats.RemovedOuters[tmID], "filledInners", snapConfig.Stats.FilledInners[tmID])
+				slog.Info("snapped", append([]any{"tmID", tmID, "level", level}, counts...)...)
+				switch format {
+				case formatWKT:
+					fmt.Printf("-- tile matrix %d\n%s", tmID, geomhelp.WktMustEncodeSlice(newPolygons, 0))
+				case formatGeoJSON:
+					features = append(features, geojson.Feature{
+						Geometry:   geojson.Geometry{Geometry: polygonsToGeometry(newPolygons)},
+						Properties: map[string]any{"tileMatrix": tmID, "level": level},
+					})
+				}
+			}
+			if format == formatGeoJSON {
+				featureCollectionJSON, err := json.Marshal(geojson.FeatureCollection{Features: features})
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(featureCollectionJSON))
+			}
+			return nil
+		},
+	}
+}
+
+// readGeometryArg reads the (multi)polygon from the argument or stdin, as WKT or GeoJSON geometry
+func readGeometryArg(c *cli.Context) ([]geom.Polygon, error) {
+	var input string
+	switch c.NArg() {
+	case 0:
+		stdin, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		input = string(stdin)
+	case 1:
+		input = c.Args().First()
+	default:
+		return nil, errors.New("expected one geometry")
+	}
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "{") {
+		geometry, err := wkt.DecodeString(input)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the WKT geometry: %w", err)
+		}
+		return asPolygons(geometry)
+	}
+	var geoJSONGeometry geojson.Geometry
+	if err := json.Unmarshal([]byte(input), &geoJSONGeometry); err != nil {
+		return nil, fmt.Errorf("could not parse the GeoJSON geometry: %w", err)
+	}
+	polygons, err := asPolygons(geoJSONGeometry.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	openRings(polygons) // as the WKT and WKB decoders do
+	return polygons, nil
+}
+
+func asPolygons(geometry geom.Geometry) ([]geom.Polygon, error) {
+	switch geometry := geometry.(type) {
+	case geom.Polygon:
+		return []geom.Polygon{geometry}, nil
+	case geom.MultiPolygon:
+		polygons := make([]geom.Polygon, len(geometry))
+		for i, polygon := range geometry {
+			polygons[i] = polygon
+		}
+		return polygons, nil
+	default:
+		return nil, fmt.Errorf("expected a POLYGON or MULTIPOLYGON, got %T", geometry)
+	}
+}
+
+// openRings removes the closing vertex of the rings
+func openRings(polygons []geom.Polygon) {
+	for _, polygon := range polygons {
+		for i, ring := range polygon {
+			if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+				polygon[i] = ring[:len(ring)-1]
+			}
+		}
+	}
+}
+
+// countRings returns the number of polygons, rings and vertices, as log attributes
+func countRings(polygons []geom.Polygon) []any {
+	var rings, vertices int
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			rings++
+			vertices += len(ring)
+		}
+	}
+	return []any{"polygons", len(polygons), "rings", rings, "vertices", vertices}
+}
+
+// polygonsToGeometry returns a single polygon as is, multiple as a multipolygon
+func polygonsToGeometry(polygons []geom.Polygon) geom.Geometry {
+	if len(polygons) == 1 {
+		return polygons[0]
+	}
+	multiPolygon := make(geom.MultiPolygon, len(polygons))
+	for i, polygon := range polygons {
+		multiPolygon[i] = polygon
+	}
+	return multiPolygon
+}
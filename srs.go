@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/processing/gpkg"
+	"github.com/pdok/texel/tms20"
+	"github.com/pdok/texel/transform"
+)
+
+type srsMismatchMode string
+
+const (
+	srsMismatchReproject srsMismatchMode = "reproject"
+	srsMismatchFail      srsMismatchMode = "fail"
+	srsMismatchSkip      srsMismatchMode = "skip"
+)
+
+func parseSRSMismatchMode(s string) (srsMismatchMode, error) {
+	switch m := srsMismatchMode(s); m {
+	case srsMismatchReproject, srsMismatchFail, srsMismatchSkip:
+		return m, nil
+	default:
+		return srsMismatchFail, fmt.Errorf(`unknown srs mismatch mode "%s", should be "%s", "%s" or "%s"`,
+			s, srsMismatchReproject, srsMismatchFail, srsMismatchSkip)
+	}
+}
+
+// sourceTable is a table in the source, with how it is written to the targets
+type sourceTable struct {
+	gpkg.Table
+	// the table in the targets, in the CRS of the tile matrix set
+	target gpkg.Table
+	// nil if the SRS of the table is the CRS of the tile matrix set
+	transformer *transform.Transformer
+}
+
+// source returns the source GPKG for reading this table, reprojecting the features if needed
+func (t sourceTable) source(source gpkg.SourceGeopackage) processing.Source {
+	source.Table = t.Table
+	if t.transformer == nil {
+		return source
+	}
+	return processing.TransformSource(source, t.transformer.Geometry)
+}
+
+func targetTables(tables []sourceTable) []gpkg.Table {
+	targets := make([]gpkg.Table, 0, len(tables))
+	for _, table := range tables {
+		targets = append(targets, table.target)
+	}
+	return targets
+}
+
+// checkTablesSRS compares the SRS of the source tables with the CRS of the tile matrix set.
+// Depending on the mode a mismatch is reprojected, fails, or the mismatched tables are left out (and returned for the summary).
+// Failing and skipping only apply to the tables that may have polygons, as the others aren't snapped but copied as is
+// (in their own SRS).
+func checkTablesSRS(tables []gpkg.Table, tms tms20.TileMatrixSet, mode srsMismatchMode) (matched []sourceTable, mismatched []string, err error) {
+	tmsSRS := tms.AuthorityCode()
+	for _, table := range tables {
+		if transform.SameSRS(table.SRS(), tmsSRS) {
+			matched = append(matched, sourceTable{Table: table, target: table})
+			continue
+		}
+		mismatch := fmt.Sprintf("%s (%s)", table.Name, table.SRS())
+		switch {
+		case mode != srsMismatchReproject && !table.MayHavePolygons():
+			slog.Info("copying table as is, its srs does not match the crs of the tile matrix set but it has no polygons to snap",
+				"table", table.Name, "srs", table.SRS(), "tmsSRS", tmsSRS)
+			matched = append(matched, sourceTable{Table: table, target: table})
+		case mode == srsMismatchReproject:
+			reprojected, err := reprojectTable(table, tmsSRS)
+			if err != nil {
+				return nil, nil, fmt.Errorf("srs of table %s does not match the crs of the tile matrix set (%s) and cannot be reprojected: %w",
+					mismatch, tmsSRS, err)
+			}
+			slog.Info("reprojecting table", "table", table.Name, "srs", table.SRS(), "tmsSRS", tmsSRS)
+			matched = append(matched, reprojected)
+		case mode == srsMismatchSkip:
+			slog.Warn("skipping table, its srs does not match the crs of the tile matrix set", "table", table.Name, "srs", table.SRS(), "tmsSRS", tmsSRS)
+			mismatched = append(mismatched, mismatch)
+		default:
+			return nil, nil, fmt.Errorf("srs of table %s does not match the crs of the tile matrix set (%s), reproject the source or skip it with --%s=%s",
+				mismatch, tmsSRS, SRSMISMATCH, srsMismatchSkip)
+		}
+	}
+	return matched, mismatched, nil
+}
+
+func reprojectTable(table gpkg.Table, targetSRS string) (sourceTable, error) {
+	transformer, err := transform.New(table.SRS(), targetSRS)
+	if err != nil {
+		return sourceTable{}, err
+	}
+	srs, err := transform.SRSFor(targetSRS)
+	if err != nil {
+		return sourceTable{}, err
+	}
+	return sourceTable{
+		Table:       table,
+		target:      table.WithSRS(srs.Name, srs.Organization, srs.Code, srs.Definition),
+		transformer: transformer,
+	}, nil
+}
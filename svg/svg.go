@@ -0,0 +1,159 @@
+// Package svg draws polygons (before and after snapping), the pixel grid and the points in a PointIndex as SVG.
+// For reviewing snapping results, no external tools needed.
+package svg
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// maxGridLines is the maximal number of grid lines (on one axis) that are drawn. A denser grid is left out.
+const maxGridLines = 1000
+
+// palette has the colors of the layers, repeated if there are more layers
+var palette = []string{"#e41a1c", "#377eb8", "#4daf4a", "#984ea3", "#ff7f00", "#a65628", "#f781bf"}
+
+// Color returns the color for the nth layer
+func Color(n int) string {
+	return palette[n%len(palette)]
+}
+
+// Layer is a set of polygons drawn in one color
+type Layer struct {
+	Title    string
+	Color    string
+	Polygons []geom.Polygon
+	// Fill the polygons (translucent), otherwise only their outlines are drawn
+	Fill bool
+}
+
+// Grid is a square pixel grid, e.g. the tile pixels or internal pixels of a tile matrix
+type Grid struct {
+	// Origin is a corner of any of the pixels
+	Origin   [2]float64
+	CellSize float64
+}
+
+// Drawing is what is drawn, from the bottom up: the grid, the layers (in order) and the points
+type Drawing struct {
+	// Extent (in the units of the geometries) that is drawn
+	Extent geom.Extent
+	// Width of the SVG in pixels, the height follows from the extent
+	Width  float64
+	Grid   *Grid
+	Layers []Layer
+	// Points are drawn as dots, e.g. the points in a PointIndex
+	Points [][2]float64
+}
+
+// Write writes the drawing as SVG
+func (d Drawing) Write(w io.Writer) error {
+	if d.Extent.XSpan() <= 0 || d.Extent.YSpan() <= 0 {
+		return fmt.Errorf("can't draw an empty extent: %v", d.Extent)
+	}
+	ew := &errWriter{w: w}
+	scale := d.Width / d.Extent.XSpan()
+	height := d.Extent.YSpan() * scale
+	toSVG := func(point [2]float64) (float64, float64) {
+		return (point[0] - d.Extent.MinX()) * scale, (d.Extent.MaxY() - point[1]) * scale
+	}
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		num(d.Width), num(height), num(d.Width), num(height))
+	ew.printf(`<rect width="100%%" height="100%%" fill="white"/>` + "\n")
+	if d.Grid != nil {
+		d.writeGrid(ew, scale, height)
+	}
+	for i, layer := range d.Layers {
+		fill, fillOpacity := "none", "0"
+		if layer.Fill {
+			fill, fillOpacity = layer.Color, "0.25"
+		}
+		ew.printf(`<g stroke="%s" stroke-width="1.5" fill="%s" fill-opacity="%s" fill-rule="evenodd"><title>%s</title>`+"\n",
+			layer.Color, fill, fillOpacity, html.EscapeString(layer.Title))
+		for _, polygon := range layer.Polygons {
+			writePolygon(ew, polygon, layer.Color, toSVG)
+		}
+		ew.printf("</g>\n")
+		ew.printf(`<text x="8" y="%d" font-family="sans-serif" font-size="14" fill="%s">%s</text>`+"\n",
+			20*(i+1), layer.Color, html.EscapeString(layer.Title))
+	}
+	if len(d.Points) > 0 {
+		ew.printf(`<g fill="black"><title>points</title>` + "\n")
+		for _, point := range d.Points {
+			x, y := toSVG(point)
+			ew.printf(`<circle cx="%s" cy="%s" r="2"/>`+"\n", num(x), num(y))
+		}
+		ew.printf("</g>\n")
+	}
+	ew.printf("</svg>\n")
+	return ew.err
+}
+
+func (d Drawing) writeGrid(ew *errWriter, scale, height float64) {
+	cellSize := d.Grid.CellSize
+	if cellSize <= 0 || d.Extent.XSpan()/cellSize > maxGridLines || d.Extent.YSpan()/cellSize > maxGridLines {
+		return // too dense to be of any help
+	}
+	ew.printf(`<g stroke="#cccccc" stroke-width="0.5"><title>grid</title>` + "\n")
+	firstX := d.Grid.Origin[0] + math.Ceil((d.Extent.MinX()-d.Grid.Origin[0])/cellSize)*cellSize
+	for x := firstX; x <= d.Extent.MaxX(); x += cellSize {
+		svgX := (x - d.Extent.MinX()) * scale
+		ew.printf(`<line x1="%s" y1="0" x2="%s" y2="%s"/>`+"\n", num(svgX), num(svgX), num(height))
+	}
+	firstY := d.Grid.Origin[1] + math.Ceil((d.Extent.MinY()-d.Grid.Origin[1])/cellSize)*cellSize
+	for y := firstY; y <= d.Extent.MaxY(); y += cellSize {
+		svgY := (d.Extent.MaxY() - y) * scale
+		ew.printf(`<line x1="0" y1="%s" x2="%s" y2="%s"/>`+"\n", num(svgY), num(d.Width), num(svgY))
+	}
+	ew.printf("</g>\n")
+}
+
+// writePolygon writes a polygon as a path, with a ring (on the points and lines from keeping them) of one vertex as a dot
+func writePolygon(ew *errWriter, polygon geom.Polygon, color string, toSVG func([2]float64) (float64, float64)) {
+	var d strings.Builder
+	for _, ring := range polygon {
+		if len(ring) == 1 {
+			x, y := toSVG(ring[0])
+			ew.printf(`<circle cx="%s" cy="%s" r="3" fill="%s"/>`+"\n", num(x), num(y), color)
+			continue
+		}
+		for i, vertex := range ring {
+			x, y := toSVG(vertex)
+			if i == 0 {
+				d.WriteString("M")
+			} else {
+				d.WriteString(" L")
+			}
+			d.WriteString(num(x) + " " + num(y))
+		}
+		if len(ring) > 2 {
+			d.WriteString(" Z ")
+		}
+	}
+	if d.Len() > 0 {
+		ew.printf(`<path d="%s"/>`+"\n", strings.TrimSpace(d.String()))
+	}
+}
+
+// num formats a number in SVG pixels, a hundredth of a pixel is precise enough
+func num(f float64) string {
+	return fmt.Sprintf("%.2f", f)
+}
+
+// errWriter keeps the first error of writing, so not every write needs to be checked
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, a...)
+}
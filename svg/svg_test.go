@@ -0,0 +1,49 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrawing_Write(t *testing.T) {
+	drawing := Drawing{
+		Extent: geom.Extent{0, 0, 4, 2},
+		Width:  200,
+		Grid:   &Grid{Origin: [2]float64{-10, -10}, CellSize: 1},
+		Layers: []Layer{
+			{Title: "source", Color: "black", Polygons: []geom.Polygon{{{{0.5, 0.5}, {3.5, 0.5}, {3.5, 1.5}}}}},
+			{Title: "tile matrix <5>", Color: Color(0), Fill: true, Polygons: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 2}}, {{3, 0.5}, {3.5, 1}, {3.5, 0.5}}},
+				{{{1, 1}, {2, 1}}},
+				{{{3, 1}}},
+			}},
+		},
+		Points: [][2]float64{{0.5, 0.5}},
+	}
+	var sb strings.Builder
+	require.NoError(t, drawing.Write(&sb))
+	svg := sb.String()
+
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="200.00" height="100.00" viewBox="0 0 200.00 100.00">`))
+	assert.Equal(t, 5+3, strings.Count(svg, "<line "), "grid lines at x = 0..4 and y = 0..2")
+	assert.Contains(t, svg, `<path d="M25.00 75.00 L175.00 75.00 L175.00 25.00 Z"/>`, "y is flipped")
+	assert.Contains(t, svg, `<path d="M0.00 100.00 L200.00 100.00 L200.00 0.00 Z M150.00 75.00 L175.00 50.00 L175.00 75.00 Z"/>`, "with inner ring")
+	assert.Contains(t, svg, `<path d="M50.00 50.00 L100.00 50.00"/>`, "line, not closed")
+	assert.Contains(t, svg, `<circle cx="150.00" cy="50.00" r="3" fill="#e41a1c"/>`, "point")
+	assert.Contains(t, svg, `<circle cx="25.00" cy="75.00" r="2"/>`, "point index point")
+	assert.Contains(t, svg, "tile matrix &lt;5&gt;")
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+}
+
+func TestDrawing_Write_denseGrid(t *testing.T) {
+	drawing := Drawing{Extent: geom.Extent{0, 0, 4000, 2}, Width: 200, Grid: &Grid{CellSize: 1}}
+	var sb strings.Builder
+	require.NoError(t, drawing.Write(&sb))
+	assert.NotContains(t, sb.String(), "<line ")
+
+	assert.Error(t, Drawing{Extent: geom.Extent{0, 0, 0, 2}, Width: 200}.Write(&sb))
+}
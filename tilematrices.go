@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/processing/gpkg"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+)
+
+const autoTileMatrices = `auto`
+
+// fraction of the (shortest) segments in the source that should at least be affected by snapping, for the auto selection
+const autoSegmentLengthFraction = 0.05
+
+// tileMatrixRange selects tile matrices by scale denominator and/or resolution (cell size). A max of 0 means no upper bound.
+type tileMatrixRange struct {
+	MinScaleDenominator float64 `json:"minScaleDenominator"`
+	MaxScaleDenominator float64 `json:"maxScaleDenominator"`
+	MinResolution       float64 `json:"minResolution"`
+	MaxResolution       float64 `json:"maxResolution"`
+}
+
+// parseTileMatrices resolves the tile matrices flag, either a JSON array of IDs or a JSON object with a tileMatrixRange.
+// The auto selection needs the source, so it is left to autoSelectTileMatrices.
+func parseTileMatrices(s string, tms tms20.TileMatrixSet) (tileMatrixIDs []tms20.TMID, auto bool, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == autoTileMatrices:
+		return nil, true, nil
+	case strings.HasPrefix(s, "{"):
+		var tmRange tileMatrixRange
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&tmRange); err != nil {
+			return nil, false, fmt.Errorf("could not parse tile matrix range: %w", err)
+		}
+		tileMatrixIDs = tms.TileMatrixIDsByScaleDenominator(tmRange.MinScaleDenominator, tmRange.MaxScaleDenominator)
+		byResolution := tms.TileMatrixIDsByCellSize(tmRange.MinResolution, tmRange.MaxResolution)
+		tileMatrixIDs = intersectTileMatrixIDs(tileMatrixIDs, byResolution)
+		slog.Info("selected tile matrices", "tmIDs", tileMatrixIDs)
+	default:
+		if err = json.Unmarshal([]byte(s), &tileMatrixIDs); err != nil {
+			return nil, false, err
+		}
+	}
+	if len(tileMatrixIDs) == 0 {
+		return nil, false, errors.New("no tile matrices selected")
+	}
+	return tileMatrixIDs, false, nil
+}
+
+// autoSelectTileMatrices selects the tile matrices for which snapping actually changes the source,
+// that is where the internal pixels are larger than the short segments in the source.
+func autoSelectTileMatrices(source gpkg.SourceGeopackage, tables []sourceTable, tms tms20.TileMatrixSet, snapConfig snap.Config) ([]tms20.TMID, error) {
+	slog.Info("determining the vertex spacing of the source", "tms", tms.ID)
+	var histogram processing.SegmentLengthHistogram
+	for _, table := range tables {
+		histogram.Add(table.source(source))
+	}
+	spacing := histogram.Percentile(autoSegmentLengthFraction)
+	if spacing == 0 {
+		return nil, errors.New("no polygon segments in the source, cannot select tile matrices automatically")
+	}
+	var tileMatrixIDs []tms20.TMID
+	for _, tmID := range tms.TileMatrixIDsByCellSize(0, 0) {
+		if tms.TileMatrices[tmID].CellSize/float64(snapConfig.InternalPixelResolutionFor(tmID)) >= spacing {
+			tileMatrixIDs = append(tileMatrixIDs, tmID)
+		}
+	}
+	if len(tileMatrixIDs) == 0 {
+		return nil, fmt.Errorf("no tile matrices with internal pixels larger than the vertex spacing (%v)", spacing)
+	}
+	slog.Info("selected tile matrices", "tms", tms.ID, "vertexSpacing", spacing, "tmIDs", tileMatrixIDs)
+	return tileMatrixIDs, nil
+}
+
+func intersectTileMatrixIDs(a, b []tms20.TMID) []tms20.TMID {
+	var intersection []tms20.TMID
+	for _, id := range a {
+		if slices.Contains(b, id) {
+			intersection = append(intersection, id)
+		}
+	}
+	return intersection
+}
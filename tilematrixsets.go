@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/pointindex"
+	"github.com/pdok/texel/processing"
+	"github.com/pdok/texel/processing/gpkg"
+	"github.com/pdok/texel/snap"
+	"github.com/pdok/texel/tms20"
+)
+
+var unsafeFileNameCharsRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// tileMatrixSetRun is a tile matrix set with its tile matrices, tables and targets in a run
+type tileMatrixSetRun struct {
+	tms               tms20.TileMatrixSet
+	tileMatrixIDs     []tms20.TMID
+	autoTileMatrixIDs bool
+	// whether the tile matrix set is a quad tree, determined once instead of for every polygon
+	quadTree bool
+	// the source tables (by name) that are processed for this tile matrix set
+	tables  map[string]sourceTable
+	targets map[tms20.TMID]*gpkg.TargetGeopackage
+}
+
+// parseTileMatrixSetPair parses a tile matrix set with its tile matrices, as in <tms>:<tile matrices>.
+// E.g. NetherlandsRDNewQuad:[5,6,7], WebMercatorQuad:auto or wmts.xml#EPSG:28992:{"minScaleDenominator":10000}
+func parseTileMatrixSetPair(pair string) (*tileMatrixSetRun, error) {
+	for i, r := range pair {
+		if r != ':' {
+			continue
+		}
+		tileMatrices := pair[i+1:]
+		if !strings.HasPrefix(tileMatrices, "[") && !strings.HasPrefix(tileMatrices, "{") && tileMatrices != autoTileMatrices {
+			continue
+		}
+		return newTileMatrixSetRun(pair[:i], tileMatrices)
+	}
+	return nil, fmt.Errorf(`could not parse "%s", should be <tile matrix set>:<tile matrices>`, pair)
+}
+
+func newTileMatrixSetRun(tileMatrixSet string, tileMatrices string) (*tileMatrixSetRun, error) {
+	tms, err := tms20.LoadTileMatrixSet(tileMatrixSet)
+	if err != nil {
+		return nil, err
+	}
+	tileMatrixIDs, auto, err := parseTileMatrices(tileMatrices, tms)
+	if err != nil {
+		return nil, fmt.Errorf("tile matrix set %s: %w", tileMatrixSet, err)
+	}
+	return &tileMatrixSetRun{tms: tms, tileMatrixIDs: tileMatrixIDs, autoTileMatrixIDs: auto, quadTree: pointindex.IsQuadTree(tms) == nil}, nil
+}
+
+// targetPathFmt returns the format for the target paths of the tile matrices.
+// With multiple tile matrix sets in a run, the ID of the tile matrix set is added. E.g. target_WebMercatorQuad_6.gpkg
+func (r *tileMatrixSetRun) targetPathFmt(target string, multiple bool) string {
+	if multiple {
+		dir, file := path.Split(target)
+		ext := path.Ext(file)
+		target = path.Join(dir, file[:len(file)-len(ext)]+"_"+unsafeFileNameCharsRegex.ReplaceAllString(r.tms.ID, "_")+ext)
+	}
+	return injectSuffixIntoPath(target)
+}
+
+// processingOptions are the options of a run that apply to every tile matrix set
+type processingOptions struct {
+	snapConfig   snap.Config
+	validateMode validateMode
+	debug        debugOptions
+	// reproDir is where a reproduction of a panic is written to
+	reproDir string
+}
+
+// processing returns how a source table is processed for this tile matrix set, false if the table is not processed
+func (r *tileMatrixSetRun) processing(tableName string, options processingOptions, report *runReport) (processing.TileMatrixSetProcessing, bool) {
+	table, ok := r.tables[tableName]
+	if !ok {
+		return processing.TileMatrixSetProcessing{}, false
+	}
+	targets := make(map[tms20.TMID]processing.Target, len(r.targets))
+	for tmID, target := range r.targets {
+		target.Table = table.target
+		targets[tmID] = target
+	}
+	tms := r.tms
+	snapConfig := options.snapConfig
+	snapConfig.Stats = report.statsFor(tms.ID, tableName)
+	snapConfig.QuadTree = &r.quadTree
+	p := processing.TileMatrixSetProcessing{
+		ID:      tms.ID,
+		Targets: targets,
+		F: func(fid int64, p geom.Polygon, tmIDs []tms20.TMID) (map[tms20.TMID][]geom.Polygon, error) {
+			snapConfig := snapConfig
+			snapConfig.Logger = slog.With("tms", tms.ID, "table", tableName, "fid", fid)
+			newPolygonsPerTileMatrix := snapFeature(fid, p, tms, tmIDs, tableName, snapConfig, report)
+			report.addSnapped(tms.ID, tableName, p, newPolygonsPerTileMatrix)
+			return newPolygonsPerTileMatrix, nil
+		},
+		Validate: func(fid int64, geometry geom.Geometry, newPolygonsPerTileMatrix map[tms20.TMID][]geom.Polygon) (map[tms20.TMID][]geom.Polygon, error) {
+			validPolygonsPerTileMatrix, findings, err := validateSnapped(fid, newPolygonsPerTileMatrix, options.validateMode)
+			options.debug.dumpFeature(fid, geometry, tms, r.tileMatrixIDs, tableName, snapConfig, failureOf(findings, err))
+			if err == nil {
+				report.addValidated(tms.ID, tableName, validPolygonsPerTileMatrix, findings)
+			}
+			return validPolygonsPerTileMatrix, err
+		},
+		OnPanic: func(panicErr processing.PanicError) error {
+			options.debug.dumpFeature(panicErr.FID, panicErr.Polygon, tms, panicErr.TileMatrixIDs, tableName, snapConfig, panicErr.Error())
+			return writeRepro(options.reproDir, tableName, tms, options, panicErr)
+		},
+	}
+	if table.transformer != nil {
+		p.Transform = table.transformer.Geometry
+	}
+	return p, true
+}
+
+// snapFeature snaps the polygon (or a part of the multipolygon) of a feature
+func snapFeature(fid int64, p geom.Polygon, tms tms20.TileMatrixSet, tmIDs []tms20.TMID, tableName string,
+	snapConfig snap.Config, report *runReport) map[tms20.TMID][]geom.Polygon {
+	newPolygonsPerTileMatrix, outside := snap.SnapPolygonOutsideGrid(p, tms, tmIDs, snapConfig)
+	if outside && snapConfig.ClipOutsideGrid {
+		report.addClipped(tms.ID, tableName, fid)
+	} else if outside {
+		report.addIgnored(tms.ID, tableName, fid)
+	}
+	return newPolygonsPerTileMatrix
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/pointindex"
+	"github.com/pdok/texel/tms20"
+	"github.com/urfave/cli/v2"
+)
+
+const CRS string = `crs`
+const BBOX string = `bbox`
+const ORIGIN string = `origin`
+const CORNER string = `corner`
+const TILESIZE string = `tilesize`
+const LEVELS string = `levels`
+const CELLSIZE string = `cellsize`
+const SCALEDENOMINATOR string = `scaledenominator`
+const METERSPERUNIT string = `metersperunit`
+const ORDEREDAXES string = `orderedaxes`
+const ID string = `id`
+const TITLE string = `title`
+const OUTPUT string = `output`
+
+func tmsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tms",
+		Usage: "Tile matrix set tools",
+		Subcommands: []*cli.Command{
+			tmsCreateCommand(),
+			tmsWMTSCommand(),
+		},
+	}
+}
+
+//nolint:funlen
+func tmsCreateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "create",
+		Usage: "Create a quad tree tile matrix set (JSON) for a CRS and region",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     CRS,
+				Usage:    `URI of the CRS or a JSON object as in a tile matrix set. E.g.: http://www.opengis.net/def/crs/EPSG/0/28992`,
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  BBOX,
+				Usage: `Extent the top tile should cover, in XY order. JSON array of minx, miny, maxx, maxy. E.g.: [-285401.92,22598.08,595401.92,903401.92]`,
+			},
+			&cli.StringFlag{
+				Name:  ORIGIN,
+				Usage: `Point of origin of the tile matrices, in XY order. JSON array of x, y. Overrides the corner of the bbox. E.g.: [-285401.92,903401.92]`,
+			},
+			&cli.StringFlag{
+				Name:  CORNER,
+				Usage: `Corner of origin, topLeft or bottomLeft`,
+				Value: string(tms20.TopLeft),
+			},
+			&cli.UintFlag{
+				Name:  TILESIZE,
+				Usage: "Width and height of the tiles in pixels",
+				Value: 256,
+			},
+			&cli.UintFlag{
+				Name:     LEVELS,
+				Usage:    "Number of tile matrices",
+				Required: true,
+			},
+			&cli.Float64Flag{
+				Name:  CELLSIZE,
+				Usage: "Cell size of the top tile matrix. Defaults to the smallest one for which the top tile covers the bbox",
+			},
+			&cli.Float64Flag{
+				Name:  SCALEDENOMINATOR,
+				Usage: "Scale denominator of the top tile matrix, alternative to the cell size",
+			},
+			&cli.Float64Flag{
+				Name:  METERSPERUNIT,
+				Usage: "Meters per unit of the CRS, for converting between cell size and scale denominator. E.g. 111319.49079327357 for degrees",
+				Value: 1,
+			},
+			&cli.StringSliceFlag{
+				Name:  ORDEREDAXES,
+				Usage: "Names of the axes in the order of the CRS, repeated. Needed if the axis order can't be determined from the CRS. E.g.: --orderedaxes=X --orderedaxes=Y",
+			},
+			&cli.StringFlag{
+				Name:  ID,
+				Usage: "ID of the tile matrix set",
+			},
+			&cli.StringFlag{
+				Name:  TITLE,
+				Usage: "Title of the tile matrix set",
+			},
+			&cli.UintFlag{
+				Name:    INTERNALPIXELRESOLUTION,
+				Aliases: []string{"ipr"},
+				Usage:   "Internal pixel resolution to report the deviation of the deepest tile matrix for",
+				Value:   pointindex.VectorTileInternalPixelResolution,
+			},
+			&cli.StringFlag{
+				Name:    OUTPUT,
+				Aliases: []string{"o"},
+				Usage:   "File to write the tile matrix set to, instead of stdout",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			opts, err := quadTreeOptionsFromFlags(c)
+			if err != nil {
+				return err
+			}
+			tms, err := tms20.NewQuadTreeTileMatrixSet(opts)
+			if err != nil {
+				return err
+			}
+			if err = pointindex.IsQuadTree(tms); err != nil {
+				return fmt.Errorf("built tile matrix set is not a quad tree: %w", err)
+			}
+			deepestTMID := tms20.TMID(opts.Levels - 1)
+			stats, deviationInUnits, deviationInPixels, err := pointindex.DeviationStats(tms, deepestTMID, c.Uint(INTERNALPIXELRESOLUTION))
+			if err != nil {
+				return err
+			}
+			slog.Info("deviation stats", "tmID", deepestTMID, "stats", stats)
+			if deviationInPixels >= 1 {
+				slog.Warn("(largest) deviation is larger than 1 tile pixel on the deepest matrix", "tmID", deepestTMID, "deviationInUnits", deviationInUnits)
+			}
+
+			tmsJSON, err := json.MarshalIndent(&tms, "", "  ")
+			if err != nil {
+				return err
+			}
+			return writeOutput(c, tmsJSON)
+		},
+	}
+}
+
+func tmsWMTSCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "wmts",
+		Usage: "Export a tile matrix set as a WMTS 1.0 TileMatrixSet (XML)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     TILEMATRIXSET,
+				Aliases:  []string{"tms"},
+				Usage:    `ID of a (built-in) tile matrix set or a tile matrix set JSON file. E.g.: NetherlandsRDNewQuad`,
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    OUTPUT,
+				Aliases: []string{"o"},
+				Usage:   "File to write the TileMatrixSet to, instead of stdout",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			tms, err := tms20.LoadTileMatrixSet(c.String(TILEMATRIXSET))
+			if err != nil {
+				return err
+			}
+			wmtsXML, err := tms20.MarshalWMTSTileMatrixSet(&tms)
+			if err != nil {
+				return err
+			}
+			return writeOutput(c, wmtsXML)
+		},
+	}
+}
+
+// writeOutput writes to the file of the output flag, or stdout if not set
+func writeOutput(c *cli.Context, data []byte) error {
+	if !c.IsSet(OUTPUT) {
+		fmt.Println(string(data))
+		return nil
+	}
+	return os.WriteFile(c.String(OUTPUT), data, 0o644) //nolint:gosec
+}
+
+func quadTreeOptionsFromFlags(c *cli.Context) (tms20.QuadTreeOptions, error) {
+	crs, err := tms20.ParseCRS(c.String(CRS))
+	if err != nil {
+		return tms20.QuadTreeOptions{}, err
+	}
+	opts := tms20.QuadTreeOptions{
+		ID:               c.String(ID),
+		Title:            c.String(TITLE),
+		CRS:              crs,
+		OrderedAxes:      c.StringSlice(ORDEREDAXES),
+		CornerOfOrigin:   tms20.CornerOfOrigin(c.String(CORNER)),
+		TileSize:         c.Uint(TILESIZE),
+		Levels:           c.Uint(LEVELS),
+		CellSize:         c.Float64(CELLSIZE),
+		ScaleDenominator: c.Float64(SCALEDENOMINATOR),
+		MetersPerUnit:    c.Float64(METERSPERUNIT),
+	}
+	if c.IsSet(BBOX) {
+		var bbox geom.Extent
+		if err = json.Unmarshal([]byte(c.String(BBOX)), &bbox); err != nil {
+			return opts, fmt.Errorf("could not parse bbox: %w", err)
+		}
+		opts.BoundingBox = &bbox
+	}
+	if c.IsSet(ORIGIN) {
+		var origin geom.Point
+		if err = json.Unmarshal([]byte(c.String(ORIGIN)), &origin); err != nil {
+			return opts, fmt.Errorf("could not parse origin: %w", err)
+		}
+		opts.PointOfOrigin = &origin
+	}
+	return opts, nil
+}
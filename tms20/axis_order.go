@@ -0,0 +1,174 @@
+package tms20
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	latLonOrderedAxesRegex = regexp.MustCompile(`^(n,e|y,x|lat,lon|latitude,longitude|northing,easting|n\(y\),e\(x\))`)
+	lonLatOrderedAxesRegex = regexp.MustCompile(`^(e,n|x,y|lon,lat|long,lat|longitude,latitude|easting,northing|e\(x\),n\(y\))`)
+	// e.g. AXIS["easting (X)",east,ORDER[1]] (WKT2) or AXIS["Easting",EAST] (WKT1)
+	wktAxisRegex = regexp.MustCompile(`(?i)AXIS\[\s*"([^"]*)"\s*,\s*([a-z]+)`)
+	// e.g. "geodetic latitude (Lat)"
+	wktAxisNameAbbreviationRegex = regexp.MustCompile(`^(.*?)\s*\(([^)]+)\)$`)
+	// the identifier of the CRS itself is the last element, e.g. ID["EPSG",28992]] (WKT2) or AUTHORITY["EPSG","28992"]] (WKT1)
+	wktIDRegex = regexp.MustCompile(`(?i)(?:ID|AUTHORITY)\[\s*"([^"]+)"\s*,\s*"?([^",\]]+)"?[^\]]*\]\s*\]\s*$`)
+)
+
+// axisOrderIsLatLon determines from the axes' names or abbreviations whether the first axis is the northing
+func axisOrderIsLatLon(orderedAxes []string) (bool, error) {
+	if len(orderedAxes) < 2 {
+		return false, errors.New(`could not determine if (empty or single) ordered axes are in lat/lon order`)
+	}
+	orderedAxesStr := []byte(strings.ToLower(fmt.Sprintf(`%s,%s`, orderedAxes[0], orderedAxes[1])))
+	if latLonOrderedAxesRegex.Match(orderedAxesStr) {
+		return true, nil
+	} else if lonLatOrderedAxesRegex.Match(orderedAxesStr) {
+		return false, nil
+	}
+	return false, errors.New(`could not determine if ordered axes are in lat/lon order`)
+}
+
+// axesAreLatLon determines from the axes of a CRS definition whether the first axis is the northing
+func axesAreLatLon(axes []ProjJSONAxis) (bool, error) {
+	if len(axes) < 2 {
+		return false, errors.New(`could not determine axis order from less than 2 axes`)
+	}
+	// abbreviations first, because e.g. both axes of polar stereographic projections have direction north or south
+	if isLatLon, err := axisOrderIsLatLon([]string{axes[0].Abbreviation, axes[1].Abbreviation}); err == nil {
+		return isLatLon, nil
+	}
+	first, second := strings.ToLower(axes[0].Direction), strings.ToLower(axes[1].Direction)
+	switch {
+	case isNorthSouth(first) && isEastWest(second):
+		return true, nil
+	case isEastWest(first) && isNorthSouth(second):
+		return false, nil
+	}
+	if isLatLon, err := axisOrderIsLatLon([]string{axes[0].Name, axes[1].Name}); err == nil {
+		return isLatLon, nil
+	}
+	return false, fmt.Errorf(`could not determine axis order from axes %v`, axes)
+}
+
+func isNorthSouth(direction string) bool {
+	return direction == "north" || direction == "south"
+}
+
+func isEastWest(direction string) bool {
+	return direction == "east" || direction == "west"
+}
+
+// parseProjJSON takes the identifier and the axes of the coordinate system from a ProjJSON CRS definition
+func parseProjJSON(projJSON map[string]interface{}) (ProjJSON, error) {
+	var result ProjJSON
+	if rawID, ok := projJSON["id"]; ok {
+		id, ok := rawID.(map[string]interface{})
+		if !ok {
+			return result, fmt.Errorf(`ProjJSON id is not an object but a %T`, rawID)
+		}
+		result.ID = &ProjJSONID{
+			AuthorityName: projJSONString(id["authority"]),
+			AuthorityCode: projJSONString(id["code"]), // can be a string or a number
+		}
+	}
+	coordinateSystem := findProjJSONCoordinateSystem(projJSON)
+	rawAxes, _ := coordinateSystem["axis"].([]interface{})
+	for _, rawAxis := range rawAxes {
+		axis, ok := rawAxis.(map[string]interface{})
+		if !ok {
+			return result, fmt.Errorf(`ProjJSON axis is not an object but a %T`, rawAxis)
+		}
+		result.Axes = append(result.Axes, ProjJSONAxis{
+			Name:         projJSONString(axis["name"]),
+			Abbreviation: projJSONString(axis["abbreviation"]),
+			Direction:    projJSONString(axis["direction"]),
+		})
+	}
+	if result.ID == nil && len(result.Axes) == 0 {
+		return result, fmt.Errorf(`could not find an id or coordinate system axes in ProjJSON "%v"`, projJSON)
+	}
+	return result, nil
+}
+
+// findProjJSONCoordinateSystem finds the (horizontal) coordinate system, also in bound and compound CRSs
+func findProjJSONCoordinateSystem(projJSON map[string]interface{}) map[string]interface{} {
+	if coordinateSystem, ok := projJSON["coordinate_system"].(map[string]interface{}); ok {
+		return coordinateSystem
+	}
+	if sourceCRS, ok := projJSON["source_crs"].(map[string]interface{}); ok {
+		return findProjJSONCoordinateSystem(sourceCRS)
+	}
+	if components, ok := projJSON["components"].([]interface{}); ok && len(components) > 0 {
+		if firstComponent, ok := components[0].(map[string]interface{}); ok {
+			return findProjJSONCoordinateSystem(firstComponent)
+		}
+	}
+	return nil
+}
+
+func projJSONString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+// parseWKT2 takes the identifier and the (first two) axes from a WKT2 (or WKT1) CRS definition.
+// Only the axes of the outermost CRS, not those of e.g. the BASEGEOGCRS (or GEOGCS) of a projected CRS.
+func parseWKT2(wkt string) (ProjJSON, error) {
+	var result ProjJSON
+	if idParts := wktIDRegex.FindStringSubmatch(wkt); idParts != nil {
+		result.ID = &ProjJSONID{AuthorityName: idParts[1], AuthorityCode: idParts[2]}
+	}
+	for _, element := range wktTopLevelElements(wkt) {
+		axisParts := wktAxisRegex.FindStringSubmatch(element)
+		if axisParts == nil || !strings.HasPrefix(strings.ToUpper(element), "AXIS") || len(result.Axes) == 2 {
+			continue
+		}
+		axis := ProjJSONAxis{Name: axisParts[1], Direction: axisParts[2]}
+		if nameParts := wktAxisNameAbbreviationRegex.FindStringSubmatch(axis.Name); nameParts != nil {
+			axis.Name = nameParts[1]
+			axis.Abbreviation = nameParts[2]
+		}
+		result.Axes = append(result.Axes, axis)
+	}
+	if result.ID == nil && len(result.Axes) == 0 {
+		return result, fmt.Errorf(`could not find an id or axes in WKT "%v"`, wkt)
+	}
+	return result, nil
+}
+
+// wktTopLevelElements returns the (bracketed) elements directly within the outermost element of a WKT definition
+func wktTopLevelElements(wkt string) []string {
+	var elements []string
+	depth, start, quoted := 0, -1, false
+	for i, r := range wkt {
+		switch {
+		case r == '"': // an escaped quote is doubled, so toggles twice
+			quoted = !quoted
+		case quoted:
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+			if depth == 1 && start >= 0 {
+				elements = append(elements, strings.TrimSpace(wkt[start:i+1]))
+				start = -1
+			}
+		case r == ',' && depth == 1:
+			start = i + 1
+		}
+	}
+	return elements
+}
@@ -0,0 +1,162 @@
+package tms20
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	wkt2RDNew = `PROJCRS["Amersfoort / RD New",BASEGEOGCRS["Amersfoort",DATUM["Amersfoort",` +
+		`ELLIPSOID["Bessel 1841",6377397.155,299.1528128,LENGTHUNIT["metre",1]]],` +
+		`PRIMEM["Greenwich",0,ANGLEUNIT["degree",0.0174532925199433]],ID["EPSG",4289]],` +
+		`CONVERSION["RD New",METHOD["Oblique Stereographic",ID["EPSG",9809]]],` +
+		`CS[Cartesian,2],AXIS["easting (X)",east,ORDER[1],LENGTHUNIT["metre",1]],` +
+		`AXIS["northing (Y)",north,ORDER[2],LENGTHUNIT["metre",1]],` +
+		`USAGE[SCOPE["Engineering survey, topographic mapping."],AREA["Netherlands - onshore"],BBOX[50.75,3.2,53.7,7.22]],` +
+		`ID["EPSG",28992]]`
+	wkt2WGS84 = `GEOGCRS["WGS 84",DATUM["World Geodetic System 1984",ELLIPSOID["WGS 84",6378137,298.257223563]],` +
+		`CS[ellipsoidal,2],AXIS["geodetic latitude (Lat)",north,ORDER[1]],AXIS["geodetic longitude (Lon)",east,ORDER[2]],` +
+		`ID["EPSG",4326]]`
+	wkt2UPSNorth = `PROJCRS["WGS 84 / UPS North (E,N)",BASEGEOGCRS["WGS 84",DATUM["World Geodetic System 1984",` +
+		`ELLIPSOID["WGS 84",6378137,298.257223563]]],CONVERSION["Universal Polar Stereographic North",METHOD["Polar Stereographic (variant A)"]],` +
+		`CS[Cartesian,2],AXIS["easting (E)",south,MERIDIAN[90,ANGLEUNIT["degree",0.0174532925199433]]],` +
+		`AXIS["northing (N)",south,MERIDIAN[180,ANGLEUNIT["degree",0.0174532925199433]]],ID["EPSG",5041]]`
+	wkt1Custom = `PROJCS["Custom",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]]],` +
+		`PROJECTION["Transverse_Mercator"],UNIT["metre",1],AXIS["Northing",NORTH],AXIS["Easting",EAST]]`
+	wkt1UTM31N = `PROJCS["WGS 84 / UTM zone 31N",GEOGCS["WGS 84",DATUM["WGS_1984",` +
+		`SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],` +
+		`PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],` +
+		`AXIS["Latitude",NORTH],AXIS["Longitude",EAST],AUTHORITY["EPSG","4326"]],` +
+		`PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",3],` +
+		`PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",500000],PARAMETER["false_northing",0],` +
+		`UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Easting",EAST],AXIS["Northing",NORTH],AUTHORITY["EPSG","32631"]]`
+)
+
+func TestIsLatLon(t *testing.T) {
+	tests := []struct {
+		name          string
+		crs           interface{}
+		want          bool
+		wantAuthority string
+		wantCode      string
+		wantErr       bool
+	}{
+		{name: "EPSG 4326 uri, from table", crs: "http://www.opengis.net/def/crs/EPSG/0/4326",
+			want: true, wantAuthority: "EPSG", wantCode: "4326"},
+		{name: "EPSG 28992 urn, from table", crs: "urn:ogc:def:crs:EPSG::28992",
+			want: false, wantAuthority: "EPSG", wantCode: "28992"},
+		{name: "CRS84", crs: "http://www.opengis.net/def/crs/OGC/1.3/CRS84",
+			want: false, wantAuthority: "OGC", wantCode: "CRS84"},
+		{name: "unknown authority uri", crs: "http://www.opengis.net/def/crs/CUSTOM/0/1",
+			wantErr: true, wantAuthority: "CUSTOM", wantCode: "1"},
+		{name: "WKT2 RD New", crs: map[string]interface{}{"wkt": wkt2RDNew},
+			want: false, wantAuthority: "EPSG", wantCode: "28992"},
+		{name: "WKT2 WGS 84", crs: map[string]interface{}{"wkt": wkt2WGS84},
+			want: true, wantAuthority: "EPSG", wantCode: "4326"},
+		{name: "WKT2 polar, both directions south", crs: map[string]interface{}{"wkt": wkt2UPSNorth},
+			want: false, wantAuthority: "EPSG", wantCode: "5041"},
+		{name: "WKT1 custom without id, northing first", crs: map[string]interface{}{"wkt": wkt1Custom},
+			want: true},
+		{name: "WKT1 projected with the axes of its geographic crs", crs: map[string]interface{}{"wkt": wkt1UTM31N},
+			want: false, wantAuthority: "EPSG", wantCode: "32631"},
+		{name: "ProjJSON custom without id", crs: map[string]interface{}{"wkt": map[string]interface{}{
+			"type": "GeographicCRS",
+			"name": "Custom lat/lon",
+			"coordinate_system": map[string]interface{}{
+				"subtype": "ellipsoidal",
+				"axis": []interface{}{
+					map[string]interface{}{"name": "Geodetic latitude", "abbreviation": "Lat", "direction": "north"},
+					map[string]interface{}{"name": "Geodetic longitude", "abbreviation": "Lon", "direction": "east"},
+				},
+			},
+		}}, want: true},
+		{name: "ProjJSON axes overrule the table", crs: map[string]interface{}{"wkt": map[string]interface{}{
+			"type": "ProjectedCRS",
+			"name": "EPSG 3035 but with easting first",
+			"coordinate_system": map[string]interface{}{
+				"subtype": "Cartesian",
+				"axis": []interface{}{
+					map[string]interface{}{"name": "Easting", "abbreviation": "", "direction": "east"},
+					map[string]interface{}{"name": "Northing", "abbreviation": "", "direction": "north"},
+				},
+			},
+			"id": map[string]interface{}{"authority": "EPSG", "code": 3035.0},
+		}}, want: false, wantAuthority: "EPSG", wantCode: "3035"},
+		{name: "ProjJSON bound crs", crs: map[string]interface{}{"wkt": map[string]interface{}{
+			"type": "BoundCRS",
+			"source_crs": map[string]interface{}{
+				"type": "ProjectedCRS",
+				"coordinate_system": map[string]interface{}{
+					"axis": []interface{}{
+						map[string]interface{}{"name": "Northing", "abbreviation": "N", "direction": "north"},
+						map[string]interface{}{"name": "Easting", "abbreviation": "E", "direction": "east"},
+					},
+				},
+			},
+		}}, want: true},
+		{name: "ProjJSON only id, from table", crs: map[string]interface{}{"wkt": map[string]interface{}{
+			"id": map[string]interface{}{"authority": "EPSG", "code": 3035.0},
+		}}, want: true, wantAuthority: "EPSG", wantCode: "3035"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crs, err := unmarshalCRS(tt.crs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAuthority, crs.Authority())
+			assert.Equal(t, tt.wantCode, crs.Code())
+			got, err := IsLatLon(crs)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWKTCRS_MarshalJSON(t *testing.T) {
+	wkt2JSON, err := json.Marshal(wkt2WGS84)
+	require.NoError(t, err)
+	for _, rawWKT := range []string{string(wkt2JSON), `{"id":{"authority":"EPSG","code":4326}}`} {
+		rawCRS := `{"description":"WGS 84","wkt":` + rawWKT + `}`
+		var crs WKTCRS
+		require.NoError(t, json.Unmarshal([]byte(rawCRS), &crs))
+		remarshalled, err := json.Marshal(&crs)
+		require.NoError(t, err)
+		assert.JSONEq(t, rawCRS, string(remarshalled))
+	}
+}
+
+func Test_axisOrderIsLatLon(t *testing.T) {
+	tests := []struct {
+		orderedAxes []string
+		want        bool
+		wantErr     bool
+	}{
+		{orderedAxes: []string{"Lat", "Lon"}, want: true},
+		{orderedAxes: []string{"Lon", "Lat"}, want: false},
+		{orderedAxes: []string{"N", "E"}, want: true},
+		{orderedAxes: []string{"E", "N"}, want: false},
+		{orderedAxes: []string{"Y", "X"}, want: true},
+		{orderedAxes: []string{"X", "Y"}, want: false},
+		{orderedAxes: []string{"E(X)", "N(Y)"}, want: false},
+		{orderedAxes: []string{"X"}, wantErr: true},
+		{orderedAxes: []string{"Up", "Down"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.orderedAxes), func(t *testing.T) {
+			got, err := axisOrderIsLatLon(tt.orderedAxes)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
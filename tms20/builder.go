@@ -0,0 +1,176 @@
+package tms20
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// QuadTreeOptions are the parameters for building a quad tree tile matrix set with NewQuadTreeTileMatrixSet.
+// Coordinates are in XY order, regardless of the axis order of the CRS.
+type QuadTreeOptions struct {
+	ID    string
+	Title string
+	// Coordinate Reference System (CRS), e.g. parsed with ParseCRS
+	CRS CRS
+	// (Informative) names of the axes of the CRS, in the order of the CRS. Needed if the axis order can't be determined from the CRS
+	OrderedAxes []string
+	// Extent the top tile matrix (a single tile) should cover. Either this or the PointOfOrigin is required
+	BoundingBox *geom.Extent
+	// Corner of origin of the tile matrices (in XY order). Overrides the corner taken from the BoundingBox
+	PointOfOrigin *geom.Point
+	// TopLeft (default) or BottomLeft
+	CornerOfOrigin CornerOfOrigin
+	// Width and height of the tiles in pixels
+	TileSize uint
+	// Number of tile matrices, the deepest having ID Levels-1
+	Levels uint
+	// Cell size of the top tile matrix. If neither this nor the ScaleDenominator is set,
+	// the smallest cell size for which the top tile covers the BoundingBox is used
+	CellSize float64
+	// Scale denominator of the top tile matrix, alternative to the CellSize
+	ScaleDenominator float64
+	// Meters per unit of the CRS, used for converting between cell size and scale denominator. Defaults to 1
+	MetersPerUnit float64
+}
+
+// ParseCRS parses a CRS from a URI (e.g. http://www.opengis.net/def/crs/EPSG/0/28992)
+// or a JSON object as in the crs property of a tile matrix set (e.g. {"wkt": ...}).
+func ParseCRS(s string) (CRS, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return unmarshalCRS(s)
+	}
+	var rawCrs map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &rawCrs); err != nil {
+		return nil, err
+	}
+	return unmarshalCRS(rawCrs)
+}
+
+// NewQuadTreeTileMatrixSet builds a tile matrix set with a single tile in the top tile matrix
+// and matrices that double in width and height (and halve in cell size) per level.
+// The result is validated, and the bounding box is that of the top tile matrix.
+//
+//nolint:cyclop
+func NewQuadTreeTileMatrixSet(opts QuadTreeOptions) (TileMatrixSet, error) {
+	tms := TileMatrixSet{
+		ID:          opts.ID,
+		Title:       opts.Title,
+		OrderedAxes: opts.OrderedAxes,
+		CRS:         opts.CRS,
+	}
+	switch {
+	case opts.CRS == nil:
+		return tms, errors.New(`crs is required`)
+	case opts.TileSize == 0:
+		return tms, errors.New(`tile size is required`)
+	case opts.Levels == 0:
+		return tms, errors.New(`at least one level is required`)
+	case opts.BoundingBox == nil && opts.PointOfOrigin == nil:
+		return tms, errors.New(`either a bounding box or a point of origin is required`)
+	case opts.CellSize != 0 && opts.ScaleDenominator != 0:
+		return tms, errors.New(`either a cell size or a scale denominator should be set, not both`)
+	}
+	corner := opts.CornerOfOrigin
+	if corner == "" {
+		corner = TopLeft
+	}
+	if corner != TopLeft && corner != BottomLeft {
+		return tms, fmt.Errorf(`unknown CornerOfOrigin: %v`, corner)
+	}
+	metersPerUnit := opts.MetersPerUnit
+	if metersPerUnit == 0 {
+		metersPerUnit = 1
+	}
+
+	cellSize := opts.CellSize
+	switch {
+	case opts.ScaleDenominator != 0:
+		cellSize = opts.ScaleDenominator * StandardizedRenderingPixelSize / metersPerUnit
+	case cellSize == 0:
+		if opts.BoundingBox == nil {
+			return tms, errors.New(`a cell size or scale denominator is required without a bounding box`)
+		}
+		cellSize = math.Max(opts.BoundingBox.XSpan(), opts.BoundingBox.YSpan()) / float64(opts.TileSize)
+	}
+	cellSize = roundFloat(cellSize, CoordPrecision)
+	if cellSize <= 0 {
+		return tms, fmt.Errorf(`cell size should be positive, got %v`, cellSize)
+	}
+	span := cellSize * float64(opts.TileSize)
+
+	var origin geom.Point
+	if opts.PointOfOrigin != nil {
+		origin = *opts.PointOfOrigin
+	} else {
+		origin = geom.Point{opts.BoundingBox.MinX(), opts.BoundingBox.MaxY()}
+		if corner == BottomLeft {
+			origin = geom.Point{opts.BoundingBox.MinX(), opts.BoundingBox.MinY()}
+		}
+	}
+	bottomLeft := geom.Point{origin.X(), roundFloat(origin.Y()-span, CoordPrecision)}
+	if corner == BottomLeft {
+		bottomLeft = origin
+	}
+	topRight := geom.Point{roundFloat(bottomLeft.X()+span, CoordPrecision), roundFloat(bottomLeft.Y()+span, CoordPrecision)}
+	if bbox := opts.BoundingBox; bbox != nil {
+		tolerance := cellSize * scaleRatioTolerance
+		if bbox.MinX() < bottomLeft.X()-tolerance || bbox.MinY() < bottomLeft.Y()-tolerance ||
+			bbox.MaxX() > topRight.X()+tolerance || bbox.MaxY() > topRight.Y()+tolerance {
+			return tms, fmt.Errorf(`the top tile (%v, %v) does not cover the bounding box %v, use a larger cell size`, bottomLeft, topRight, *bbox)
+		}
+	}
+
+	if tms.OrderedAxes == nil {
+		if isLatLon, err := IsLatLon(tms.CRS); err == nil {
+			tms.OrderedAxes = []string{"X", "Y"}
+			if isLatLon {
+				tms.OrderedAxes = []string{"Y", "X"}
+			}
+		}
+	}
+	// ToXYPoint swaps the axes if needed, which works both ways
+	crsOrigin, err := ToXYPoint(&tms, origin)
+	if err != nil {
+		return tms, err
+	}
+	crsLowerLeft, _ := ToXYPoint(&tms, bottomLeft)
+	crsUpperRight, _ := ToXYPoint(&tms, topRight)
+	tms.BoundingBox = &TwoDBoundingBox{
+		LowerLeft:  (*TwoDPoint)(&crsLowerLeft),
+		UpperRight: (*TwoDPoint)(&crsUpperRight),
+		CRS:        opts.CRS,
+	}
+
+	tms.TileMatrices = make(map[TMID]TileMatrix, opts.Levels)
+	for level := uint(0); level < opts.Levels; level++ {
+		tmID := TMID(level)
+		pointOfOrigin := TwoDPoint(crsOrigin)
+		tmCellSize := cellSize / float64(uint(1)<<level)
+		tm := TileMatrix{
+			ID:               strconv.Itoa(tmID),
+			ScaleDenominator: roundFloat(tmCellSize*metersPerUnit/StandardizedRenderingPixelSize, CoordPrecision),
+			CellSize:         tmCellSize,
+			PointOfOrigin:    &pointOfOrigin,
+			TileWidth:        opts.TileSize,
+			TileHeight:       opts.TileSize,
+			MatrixWidth:      1 << level,
+			MatrixHeight:     1 << level,
+		}
+		if corner == BottomLeft {
+			tm.CornerOfOrigin = BottomLeft
+		}
+		tms.TileMatrices[tmID] = tm
+	}
+
+	if err = tms.Validate(); err != nil {
+		return tms, fmt.Errorf(`invalid tile matrix set built: %w`, err)
+	}
+	return tms, nil
+}
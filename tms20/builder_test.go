@@ -0,0 +1,120 @@
+package tms20
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQuadTreeTileMatrixSet(t *testing.T) {
+	rd, err := ParseCRS("http://www.opengis.net/def/crs/EPSG/0/28992")
+	require.NoError(t, err)
+	wgs84, err := ParseCRS("http://www.opengis.net/def/crs/EPSG/0/4326")
+	require.NoError(t, err)
+	usFeet, err := ParseCRS("http://www.opengis.net/def/crs/EPSG/0/2263")
+	require.NoError(t, err)
+	amersfoort, err := ParseCRS("http://www.opengis.net/def/crs/EPSG/0/4289")
+	require.NoError(t, err)
+	rdExtent := geom.Extent{-285401.92, 22598.08, 595401.92, 903401.92}
+
+	tests := []struct {
+		name         string
+		opts         QuadTreeOptions
+		wantOrigin   TwoDPoint
+		wantCellSize float64
+		wantScale    float64
+		wantErr      string
+	}{
+		{
+			name:         "RD from bounding box",
+			opts:         QuadTreeOptions{CRS: rd, BoundingBox: &rdExtent, TileSize: 256, Levels: 17},
+			wantOrigin:   TwoDPoint{-285401.92, 903401.92},
+			wantCellSize: 3440.64,
+			wantScale:    1.2288e7,
+		}, {
+			name:         "RD from origin and scale",
+			opts:         QuadTreeOptions{CRS: rd, PointOfOrigin: &geom.Point{-285401.92, 903401.92}, TileSize: 256, Levels: 17, ScaleDenominator: 1.2288e7},
+			wantOrigin:   TwoDPoint{-285401.92, 903401.92},
+			wantCellSize: 3440.64,
+			wantScale:    1.2288e7,
+		}, {
+			name:         "bottom left",
+			opts:         QuadTreeOptions{CRS: rd, BoundingBox: &geom.Extent{0, 0, 100, 50}, CornerOfOrigin: BottomLeft, TileSize: 100, Levels: 3},
+			wantOrigin:   TwoDPoint{0, 0},
+			wantCellSize: 1,
+			wantScale:    1 / StandardizedRenderingPixelSize,
+		}, {
+			name:         "lat lon crs",
+			opts:         QuadTreeOptions{CRS: wgs84, BoundingBox: &geom.Extent{3, 50, 8, 54}, TileSize: 256, Levels: 3, CellSize: 0.03125, MetersPerUnit: 111319.49079327357},
+			wantOrigin:   TwoDPoint{54, 3},
+			wantCellSize: 0.03125,
+			wantScale:    0.03125 * 111319.49079327357 / StandardizedRenderingPixelSize,
+		}, {
+			name:         "crs in us feet",
+			opts:         QuadTreeOptions{CRS: usFeet, BoundingBox: &geom.Extent{900000, 100000, 1100000, 300000}, TileSize: 256, Levels: 3, MetersPerUnit: 0.3048006096012192, OrderedAxes: []string{"X", "Y"}},
+			wantOrigin:   TwoDPoint{900000, 300000},
+			wantCellSize: 781.25,
+			wantScale:    781.25 * 0.3048006096012192 / StandardizedRenderingPixelSize,
+		}, {
+			name:         "unlisted geographic crs",
+			opts:         QuadTreeOptions{CRS: amersfoort, BoundingBox: &geom.Extent{3, 50, 8, 54}, TileSize: 256, Levels: 3, CellSize: 0.03125, MetersPerUnit: 111319.49079327357, OrderedAxes: []string{"Lat", "Lon"}},
+			wantOrigin:   TwoDPoint{54, 3},
+			wantCellSize: 0.03125,
+			wantScale:    0.03125 * 111319.49079327357 / StandardizedRenderingPixelSize,
+		}, {
+			name:    "cell size too small",
+			opts:    QuadTreeOptions{CRS: rd, BoundingBox: &rdExtent, TileSize: 256, Levels: 17, CellSize: 1000},
+			wantErr: "does not cover the bounding box",
+		}, {
+			name:    "cell size and scale",
+			opts:    QuadTreeOptions{CRS: rd, BoundingBox: &rdExtent, TileSize: 256, Levels: 17, CellSize: 1000, ScaleDenominator: 1000},
+			wantErr: "not both",
+		}, {
+			name:    "no extent",
+			opts:    QuadTreeOptions{CRS: rd, TileSize: 256, Levels: 17},
+			wantErr: "either a bounding box or a point of origin is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tms, err := NewQuadTreeTileMatrixSet(tt.opts)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, tms.TileMatrices, int(tt.opts.Levels))
+			for tmID, tm := range tms.TileMatrices {
+				assert.Equal(t, tt.wantOrigin, *tm.PointOfOrigin)
+				assert.InDelta(t, tt.wantCellSize/float64(uint(1)<<tmID), tm.CellSize, 1e-9)
+				assert.InEpsilon(t, tt.wantScale/float64(uint(1)<<tmID), tm.ScaleDenominator, 1e-9)
+				assert.Equal(t, uint(1)<<tmID, tm.MatrixWidth)
+				assert.Equal(t, tm.MatrixWidth, tm.MatrixHeight)
+			}
+		})
+	}
+}
+
+func TestNewQuadTreeTileMatrixSet_sameAsEmbedded(t *testing.T) {
+	embedded, err := LoadEmbeddedTileMatrixSet("NetherlandsRDNewQuad")
+	require.NoError(t, err)
+	rd, err := ParseCRS("http://www.opengis.net/def/crs/EPSG/0/28992")
+	require.NoError(t, err)
+	built, err := NewQuadTreeTileMatrixSet(QuadTreeOptions{
+		CRS:         rd,
+		BoundingBox: &geom.Extent{-285401.92, 22598.08, 595401.92, 903401.92},
+		TileSize:    256,
+		Levels:      uint(len(embedded.TileMatrices)),
+	})
+	require.NoError(t, err)
+	for tmID := range embedded.TileMatrices {
+		bottomLeft, topRight, err := embedded.MatrixBoundingBox(tmID)
+		require.NoError(t, err)
+		builtBottomLeft, builtTopRight, err := built.MatrixBoundingBox(tmID)
+		require.NoError(t, err)
+		assert.InDeltaSlice(t, bottomLeft[:], builtBottomLeft[:], 1e-6)
+		assert.InDeltaSlice(t, topRight[:], builtTopRight[:], 1e-6)
+	}
+}
@@ -2,10 +2,9 @@ package tms20
 
 var (
 	// Extracted from EPSG database v10.096
-	// recognized as lat/lon: `^(e,n|x,y|lon,lat|e\(x\),n\(y\))`
-	// recognized as lon/lat: `^(n,e|y,x|lat|lon)`
+	// true if the first axis is the northing (e.g. lat, n or y), false if it is the easting (e.g. lon, e or x).
 	// The rest was excluded/ignored
-	// TODO Find a better/neater way, e.g. go-spatial/proj?
+	// Only used as a fallback for CRSs given by (bare) URI, otherwise the axes from the definition are used.
 	epsgAxesAreLatLon = map[uint]bool{
 		2000:     false,
 		2001:     false,
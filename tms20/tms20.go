@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,10 +36,22 @@ var (
 	embeddedTileMatrixSetsCache  = make(map[string]*TileMatrixSet)
 	crsURIRegexURL               = regexp.MustCompile(`https?://.+/def/crs/(?P<authority>[^/]+)/(?P<version>[^/]*)/(?P<code>[^/]+)$`)
 	crsURIRegexURN               = regexp.MustCompile(`^urn:ogc:def:crs:(?P<authority>[^:]+):(?P<version>[^:]*):(?P<code>[^:]+)$`)
-	latLonOrderedAxesRegex       = regexp.MustCompile(`^(e,n|x,y|lon,lat|e\(x\),n\(y\))`)
-	lonLatOrderedAxesRegex       = regexp.MustCompile(`^(n,e|y,x|lat|lon)`)
 )
 
+// LoadTileMatrixSet loads a tile matrix set by the ID of an embedded one, the path to a JSON file (.json)
+// or the path to a WMTS capabilities document or TileMatrixSet fragment (.xml), optionally followed by #<WMTS identifier>.
+func LoadTileMatrixSet(idOrPath string) (TileMatrixSet, error) {
+	xmlPath, wmtsID, _ := strings.Cut(idOrPath, "#")
+	switch {
+	case strings.EqualFold(path.Ext(xmlPath), extXML):
+		return LoadWMTSTileMatrixSet(xmlPath, wmtsID)
+	case strings.EqualFold(path.Ext(idOrPath), extJSON):
+		return LoadJSONTileMatrixSet(idOrPath)
+	default:
+		return LoadEmbeddedTileMatrixSet(idOrPath)
+	}
+}
+
 func LoadJSONTileMatrixSet(path string) (TileMatrixSet, error) {
 	var tms TileMatrixSet
 	tmsJSON, err := os.ReadFile(path)
@@ -49,6 +62,9 @@ func LoadJSONTileMatrixSet(path string) (TileMatrixSet, error) {
 	if err != nil {
 		return tms, err
 	}
+	if err = tms.Validate(); err != nil {
+		return tms, fmt.Errorf(`invalid tile matrix set %s: %w`, path, err)
+	}
 	return tms, nil
 }
 
@@ -66,6 +82,9 @@ func LoadEmbeddedTileMatrixSet(id string) (TileMatrixSet, error) {
 	if err != nil {
 		return tms, err
 	}
+	if err = tms.Validate(); err != nil {
+		return tms, fmt.Errorf(`invalid tile matrix set %s: %w`, id, err)
+	}
 	embeddedTileMatrixSetsCache[id] = &tms
 	return tms, nil
 }
@@ -99,17 +118,170 @@ type TileMatrixSet struct {
 // TMID is a Tile Matrix ID
 type TMID = int
 
+const (
+	// pixel size of 96 DPI, in meters
+	dpi96RenderingPixelSize = 0.0254 / 96
+	// max relative difference between a tile matrix' cell size and the one following from its scale denominator
+	scaleRatioTolerance = 1e-3
+)
+
+// Validate checks a tile matrix set against its struct tags and the cross-field rules of the TMS 2.0 standard.
+// Errors name the offending tile matrix.
+func (tms *TileMatrixSet) Validate() error {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(tms); err != nil {
+		return err
+	}
+	if _, err := ToXYPoint(tms, TwoDPoint{}); err != nil {
+		return fmt.Errorf(`could not determine the axis order of the crs: %w`, err)
+	}
+
+	tmIDs := make([]TMID, 0, len(tms.TileMatrices))
+	for tmID := range tms.TileMatrices {
+		tmIDs = append(tmIDs, tmID)
+	}
+	sort.Ints(tmIDs)
+	metersPerUnit, knownUnit := tms.metersPerUnit()
+	var pixelSize float64
+	for _, tmID := range tmIDs {
+		tm := tms.TileMatrices[tmID]
+		if err := tm.validate(tmID); err != nil {
+			return fmt.Errorf(`tile matrix %s: %w`, tm.ID, err)
+		}
+		if !knownUnit { // then the top tile matrix determines it for the others
+			metersPerUnit, knownUnit = tm.ScaleDenominator*StandardizedRenderingPixelSize/tm.CellSize, true
+		}
+		if pixelSize == 0 { // same for all tile matrices
+			pixelSize = tm.renderingPixelSize(metersPerUnit)
+		}
+		// cellSize = scaleDenominator * 0.28mm (or 96 DPI) / metersPerUnit(crs)
+		wantCellSize := tm.ScaleDenominator * pixelSize / metersPerUnit
+		if math.Abs(tm.CellSize-wantCellSize)/wantCellSize > scaleRatioTolerance {
+			return fmt.Errorf(`tile matrix %s: cell size %v does not match scale denominator %v, expected cell size %v (at %v meters per unit)`,
+				tm.ID, tm.CellSize, tm.ScaleDenominator, wantCellSize, metersPerUnit)
+		}
+		if err := tms.validateBoundingBoxWithin(tmID); err != nil {
+			return fmt.Errorf(`tile matrix %s: %w`, tm.ID, err)
+		}
+	}
+	return nil
+}
+
+// metersPerUnit returns the meters per unit of the CRS, if known: for the listed geographic CRSs (in degrees)
+// and projected CRSs in meters. Not for others, e.g. in US feet.
+func (tms *TileMatrixSet) metersPerUnit() (float64, bool) {
+	switch authority, code := strings.ToUpper(tms.CRS.Authority()), tms.CRS.Code(); {
+	case (authority == "EPSG" || authority == "OGC") && epsgCodesInDegrees[code]:
+		return metersPerDegree, true
+	case authority == "EPSG" && epsgCodeInMeters(code):
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+// epsgCodeInMeters returns whether a (well-known) EPSG projected CRS is in meters
+func epsgCodeInMeters(code string) bool {
+	epsgCode, err := strconv.Atoi(code)
+	if err != nil {
+		return false
+	}
+	switch {
+	case epsgCode >= 32601 && epsgCode <= 32660, epsgCode >= 32701 && epsgCode <= 32760: // WGS 84 / UTM
+		return true
+	case epsgCode >= 25828 && epsgCode <= 25838: // ETRS89 / UTM
+		return true
+	default:
+		return slices.Contains([]int{2193, 3035, 3395, 3857, 3978, 5041, 5042, 5482, 28992}, epsgCode)
+	}
+}
+
+// renderingPixelSize returns the pixel size the cell size follows from with the scale denominator:
+// the standardized 0.28 mm, or 1/96 inch (96 DPI) as used by some tile matrix sets (e.g. CanadianNAD83_LCC)
+func (tm *TileMatrix) renderingPixelSize(metersPerUnit float64) float64 {
+	for _, pixelSize := range []float64{StandardizedRenderingPixelSize, dpi96RenderingPixelSize} {
+		wantCellSize := tm.ScaleDenominator * pixelSize / metersPerUnit
+		if math.Abs(tm.CellSize-wantCellSize)/wantCellSize <= scaleRatioTolerance {
+			return pixelSize
+		}
+	}
+	return StandardizedRenderingPixelSize
+}
+
+// validate checks a tile matrix against its struct tags and the rules for its variable matrix widths
+func (tm *TileMatrix) validate(tmID TMID) error {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(tm); err != nil {
+		return err
+	}
+	for i, vmw := range tm.VariableMatrixWidths {
+		if err := validate.Struct(vmw); err != nil {
+			return fmt.Errorf(`variable matrix width %d: %w`, i, err)
+		}
+		if vmw.MinTileRow > vmw.MaxTileRow || vmw.MaxTileRow >= tm.MatrixHeight {
+			return fmt.Errorf(`variable matrix width %d: rows %d to %d are not within the matrix height %d`,
+				i, vmw.MinTileRow, vmw.MaxTileRow, tm.MatrixHeight)
+		}
+		if tm.MatrixWidth%vmw.Coalesce != 0 {
+			return fmt.Errorf(`variable matrix width %d: matrix width %d is not a multiple of coalesce %d`,
+				i, tm.MatrixWidth, vmw.Coalesce)
+		}
+		for j, other := range tm.VariableMatrixWidths[:i] {
+			if vmw.MinTileRow <= other.MaxTileRow && other.MinTileRow <= vmw.MaxTileRow {
+				return fmt.Errorf(`variable matrix width %d: rows overlap with variable matrix width %d`, i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// validateBoundingBoxWithin checks whether the bounding box of the tile matrix set is within the tile matrix.
+// Only if the bounding box is in the same CRS.
+func (tms *TileMatrixSet) validateBoundingBoxWithin(tmID TMID) error {
+	bb := tms.BoundingBox
+	if bb == nil || (bb.CRS != nil && !sameCRS(bb.CRS, tms.CRS)) {
+		return nil
+	}
+	lowerLeft, err := ToXYPoint(tms, *bb.LowerLeft)
+	if err != nil {
+		return err
+	}
+	upperRight, err := ToXYPoint(tms, *bb.UpperRight)
+	if err != nil {
+		return err
+	}
+	bottomLeft, topRight, err := tms.MatrixBoundingBox(tmID)
+	if err != nil {
+		return err
+	}
+	tolerance := tms.TileMatrices[tmID].CellSize * scaleRatioTolerance
+	if lowerLeft.X() < bottomLeft.X()-tolerance || lowerLeft.Y() < bottomLeft.Y()-tolerance ||
+		upperRight.X() > topRight.X()+tolerance || upperRight.Y() > topRight.Y()+tolerance {
+		return fmt.Errorf(`bounding box %v, %v is not within the matrix %v, %v`, lowerLeft, upperRight, bottomLeft, topRight)
+	}
+	return nil
+}
+
+func sameCRS(a, b CRS) bool {
+	_, aIsReferenceSystem := a.(*ReferenceSystemCRS)
+	_, bIsReferenceSystem := b.(*ReferenceSystemCRS)
+	if aIsReferenceSystem || bIsReferenceSystem {
+		return false
+	}
+	return strings.EqualFold(a.Authority(), b.Authority()) && a.Code() == b.Code()
+}
+
 func (tms *TileMatrixSet) MarshalJSON() ([]byte, error) {
-	var tileMatrices []*TileMatrix
+	tmIDs := make([]TMID, 0, len(tms.TileMatrices))
 	for tmID := range tms.TileMatrices {
+		tmIDs = append(tmIDs, tmID)
+	}
+	sort.Ints(tmIDs) // so that unmarshalling numbers them the same
+	tileMatrices := make([]*TileMatrix, 0, len(tmIDs))
+	for _, tmID := range tmIDs {
 		tm := tms.TileMatrices[tmID]
 		tileMatrices = append(tileMatrices, &tm)
 	}
-	sort.Slice(tileMatrices, func(i, j int) bool {
-		iID, _ := strconv.ParseInt(tileMatrices[i].ID, 10, 64)
-		jID, _ := strconv.ParseInt(tileMatrices[j].ID, 10, 64)
-		return iID < jID
-	})
 	return json.Marshal(struct {
 		TileMatrixSet                     // not a pointer, because it would cause recursion to this function
 		SpecialCRS          *CRS          `json:"crs"` // pointer, because crs' structs' MarshalJSON funcs are on pointer
@@ -161,7 +333,8 @@ func unmarshalTileMatrices(rawTileMatrices interface{}) (map[TMID]TileMatrix, er
 	if !ok {
 		return nil, errors.New(`"tileMatrices" should be an array`)
 	}
-	tileMatrices := make(map[TMID]TileMatrix, len(rawTileMatricesList))
+	tileMatrixList := make([]TileMatrix, 0, len(rawTileMatricesList))
+	identifiers := make([]string, 0, len(rawTileMatricesList))
 	for _, rawTileMatrix := range rawTileMatricesList {
 		rawTileMatrixMap, ok := rawTileMatrix.(map[string]interface{})
 		if !ok {
@@ -172,15 +345,52 @@ func unmarshalTileMatrices(rawTileMatrices interface{}) (map[TMID]TileMatrix, er
 		if err != nil {
 			return nil, err
 		}
-		tmID, err := strconv.ParseInt(tileMatrix.ID, 10, 64)
-		if err != nil {
-			return nil, fmt.Errorf("only integer-like ids are supported for tile matrices: %w", err)
-		}
-		tileMatrices[int(tmID)] = tileMatrix
+		tileMatrixList = append(tileMatrixList, tileMatrix)
+		identifiers = append(identifiers, tileMatrix.ID)
+	}
+	tmIDs, err := numberTileMatrices(identifiers)
+	if err != nil {
+		return nil, err
+	}
+	tileMatrices := make(map[TMID]TileMatrix, len(tileMatrixList))
+	for i, tileMatrix := range tileMatrixList {
+		tileMatrices[tmIDs[i]] = tileMatrix
 	}
 	return tileMatrices, nil
 }
 
+// numberTileMatrices returns the TMIDs for the identifiers of the tile matrices (in order):
+// the integers the identifiers end in (e.g. 5 or EPSG:28992:5) if they all do, otherwise their index.
+// Identifiers must be unique, and so must the integers they end in.
+func numberTileMatrices(identifiers []string) ([]TMID, error) {
+	tmIDs := make([]TMID, len(identifiers))
+	byIdentifier := make(map[string]bool, len(identifiers))
+	endInInteger := true
+	for i, identifier := range identifiers {
+		if byIdentifier[identifier] {
+			return nil, fmt.Errorf(`duplicate tile matrix %s`, identifier)
+		}
+		byIdentifier[identifier] = true
+		tmID, err := strconv.Atoi(identifier[strings.LastIndex(identifier, ":")+1:])
+		endInInteger = endInInteger && err == nil
+		tmIDs[i] = tmID
+	}
+	if !endInInteger {
+		for i := range tmIDs {
+			tmIDs[i] = i
+		}
+		return tmIDs, nil
+	}
+	byTMID := make(map[TMID]string, len(identifiers))
+	for i, tmID := range tmIDs {
+		if other, exists := byTMID[tmID]; exists {
+			return nil, fmt.Errorf(`tile matrices %s and %s both end in %d`, other, identifiers[i], tmID)
+		}
+		byTMID[tmID] = identifiers[i]
+	}
+	return tmIDs, nil
+}
+
 // unmarshalCRS tries 4 different CRS types (oneOf)
 // TODO maybe there is already a library that can parse this, something like gdal/ogr
 func unmarshalCRS(rawCrs interface{}) (CRS, error) {
@@ -314,24 +524,33 @@ func (crs *URICRS) Code() string {
 type WKTCRS struct {
 	description string
 	// An object defining the CRS using the JSON encoding for Well-known text representation of coordinate reference systems 2.0
+	// (ProjJSON) or the WKT2 string itself
 	wkt         ProjJSON
-	originalWKT map[string]interface{}
+	originalWKT interface{}
 }
 
-// TODO expand the ProjJSON type
+// ProjJSON holds the parts of a PROJJSON CRS definition that are used, or the parts parsed from a WKT2 string.
+// See https://proj.org/en/latest/specifications/projjson.html
 type ProjJSON struct {
-	ID ProjJSONID `validate:"required" json:"id"`
+	ID   *ProjJSONID
+	Axes []ProjJSONAxis
 }
 
 type ProjJSONID struct {
-	AuthorityName string `validate:"required" json:"authority"`
-	AuthorityCode string `validate:"required" json:"code"` // TODO can be int cq number
+	AuthorityName string
+	AuthorityCode string
+}
+
+type ProjJSONAxis struct {
+	Name         string
+	Abbreviation string
+	Direction    string
 }
 
 func (crs *WKTCRS) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Description string                 `json:"description,omitempty"`
-		WKT         map[string]interface{} `json:"wkt"`
+		Description string      `json:"description,omitempty"`
+		WKT         interface{} `json:"wkt"`
 	}{
 		Description: crs.description,
 		WKT:         crs.originalWKT,
@@ -360,20 +579,17 @@ func (crs *WKTCRS) UnmarshalJSONFromMap(data interface{}) error {
 	if !ok {
 		return errors.New(`wkt property not found`)
 	}
-	crs.originalWKT, ok = rawWKT.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf(`wkt property is not an object but a %T`, rawWKT)
-	}
-
-	var wkt ProjJSON
-	_, err := marshmallow.UnmarshalFromJSONMap(crs.originalWKT, &wkt)
-	if err != nil {
-		return fmt.Errorf(`could not parse wkt as ProjJSON "%v"`, crs.originalWKT)
+	crs.originalWKT = rawWKT
+	var err error
+	switch wkt := rawWKT.(type) {
+	case map[string]interface{}:
+		crs.wkt, err = parseProjJSON(wkt)
+	case string:
+		crs.wkt, err = parseWKT2(wkt)
+	default:
+		err = fmt.Errorf(`wkt property is not an object or a string but a %T`, rawWKT)
 	}
-	crs.wkt = wkt
-
-	validate := validator.New(validator.WithRequiredStructEnabled())
-	return validate.Struct(crs)
+	return err
 }
 
 func (crs *WKTCRS) Description() string {
@@ -381,6 +597,9 @@ func (crs *WKTCRS) Description() string {
 }
 
 func (crs *WKTCRS) Authority() string {
+	if crs.wkt.ID == nil {
+		return ""
+	}
 	return crs.wkt.ID.AuthorityName
 }
 
@@ -389,6 +608,9 @@ func (crs *WKTCRS) Version() string {
 }
 
 func (crs *WKTCRS) Code() string {
+	if crs.wkt.ID == nil {
+		return ""
+	}
 	return crs.wkt.ID.AuthorityCode
 }
 
@@ -501,7 +723,18 @@ func (bb *TwoDBoundingBox) UnmarshalJSON(data []byte) error {
 // A 2D Point in the CRS indicated elsewhere
 type TwoDPoint [2]float64
 
+// IsLatLon determines whether the first axis of the CRS is the northing (e.g. lat, y or n).
+// The axis order is taken from the CRS definition (WKT2 or ProjJSON) if possible,
+// otherwise it is looked up by its EPSG code.
 func IsLatLon(crs CRS) (bool, error) {
+	switch c := crs.(type) {
+	case *WKTCRS:
+		if len(c.wkt.Axes) > 0 {
+			return axesAreLatLon(c.wkt.Axes)
+		}
+	case *ReferenceSystemCRS:
+		return false, errors.New(`could not determine axis order for a reference system crs`)
+	}
 	authority := crs.Authority()
 	version := crs.Version()
 	code := crs.Code()
@@ -540,19 +773,6 @@ func ToXYPoint(tms *TileMatrixSet, point [2]float64) (geom.Point, error) {
 	}
 }
 
-func axisOrderIsLatLon(orderedAxes []string) (bool, error) {
-	if len(orderedAxes) < 2 {
-		return false, errors.New(`could not determine if (empty or single) ordered axes are in lat/lon order`)
-	}
-	orderedAxesStr := []byte(strings.ToLower(fmt.Sprintf(`%s,%s`, orderedAxes[0], orderedAxes[1])))
-	if latLonOrderedAxesRegex.Match(orderedAxesStr) {
-		return true, nil
-	} else if lonLatOrderedAxesRegex.Match(orderedAxesStr) {
-		return false, nil
-	}
-	return false, errors.New(`could not determine if ordered axes are in lat/lon order`)
-}
-
 // A tile matrix, usually corresponding to a particular zoom level of a TileMatrixSet.
 type TileMatrix struct {
 	// Identifier selecting one of the scales defined in the TileMatrixSet and representing the scaleDenominator the tile.
@@ -615,6 +835,7 @@ const (
 	TopLeft    CornerOfOrigin = "topLeft"
 	BottomLeft CornerOfOrigin = "bottomLeft"
 	extJSON                   = ".json"
+	extXML                    = ".xml"
 )
 
 func (c *CornerOfOrigin) UnmarshalJSONFromMap(data interface{}) error {
@@ -640,9 +861,64 @@ type VariableMatrixWidth struct {
 	// Number of tiles in width that coalesce in a single tile for these rows
 	Coalesce uint `validate:"required,min=2" json:"coalesce"`
 	// First tile row where the coalescence factor applies for this tilematrix
-	MinTileRow uint `validate:"required,min=0" json:"minTileRow"`
+	MinTileRow uint `validate:"min=0" json:"minTileRow"` // not required, 0 is valid
 	// Last tile row where the coalescence factor applies for this tilematrix
-	MaxTileRow uint `validate:"required,min=0" json:"maxTileRow"`
+	MaxTileRow uint `validate:"min=0" json:"maxTileRow"` // not required, 0 is valid
+}
+
+// Coalesce returns the number of tiles in width that coalesce in a single tile in a row (1 if none)
+func (tm *TileMatrix) Coalesce(row uint) uint {
+	for _, vmw := range tm.VariableMatrixWidths {
+		if vmw.MinTileRow <= row && row <= vmw.MaxTileRow {
+			return vmw.Coalesce
+		}
+	}
+	return 1
+}
+
+// coalescedCol returns the column of the first tile coalesced into the tile that a column is part of in a row.
+// A coalesced tile is identified by its first column.
+func (tm *TileMatrix) coalescedCol(col, row uint) uint {
+	return col - col%tm.Coalesce(row)
+}
+
+// TileMatrixIDsByScaleDenominator returns the (sorted) IDs of the tile matrices with a scale denominator within the range (inclusive).
+// A max of 0 means no upper bound.
+func (tms *TileMatrixSet) TileMatrixIDsByScaleDenominator(minScaleDenominator, maxScaleDenominator float64) []TMID {
+	return tms.tileMatrixIDsWhere(func(tm TileMatrix) bool {
+		return withinRange(tm.ScaleDenominator, minScaleDenominator, maxScaleDenominator)
+	})
+}
+
+// TileMatrixIDsByCellSize returns the (sorted) IDs of the tile matrices with a cell size (resolution) within the range (inclusive).
+// A max of 0 means no upper bound.
+func (tms *TileMatrixSet) TileMatrixIDsByCellSize(minCellSize, maxCellSize float64) []TMID {
+	return tms.tileMatrixIDsWhere(func(tm TileMatrix) bool {
+		return withinRange(tm.CellSize, minCellSize, maxCellSize)
+	})
+}
+
+func (tms *TileMatrixSet) tileMatrixIDsWhere(f func(tm TileMatrix) bool) []TMID {
+	var tmIDs []TMID
+	for tmID, tm := range tms.TileMatrices {
+		if f(tm) {
+			tmIDs = append(tmIDs, tmID)
+		}
+	}
+	sort.Ints(tmIDs)
+	return tmIDs
+}
+
+// withinRange checks (with a small relative tolerance for rounded values) whether minimum <= f <= maximum.
+// A maximum of 0 means no upper bound.
+func withinRange(f, minimum, maximum float64) bool {
+	const tolerance = 1e-6
+	return f >= minimum*(1-tolerance) && (maximum == 0 || f <= maximum*(1+tolerance))
+}
+
+// AuthorityCode returns the authority and code of the CRS, e.g. EPSG:28992 (as in a GPKG's gpkg_spatial_ref_sys)
+func (tms *TileMatrixSet) AuthorityCode() string {
+	return fmt.Sprintf("%s:%s", strings.ToUpper(tms.CRS.Authority()), tms.CRS.Code())
 }
 
 func (tms *TileMatrixSet) SRID() uint {
@@ -661,16 +937,14 @@ func (tms *TileMatrixSet) Size(zoom uint) (*slippy.Tile, bool) {
 	return slippy.NewTile(zoom, tm.MatrixWidth, tm.MatrixHeight), true
 }
 
+// FromNative returns the tile containing the point. In rows with variable matrix widths
+// the coalesced tile is returned, identified by its first column.
 func (tms *TileMatrixSet) FromNative(zoom uint, pt geom.Point) (*slippy.Tile, bool) {
 	tm, ok := tms.TileMatrices[int(zoom)]
 	if !ok {
 		return nil, false
 	}
 
-	if tm.VariableMatrixWidths != nil {
-		panic("variable matrix widths not supported") // TODO support VariableMatrixWidths
-	}
-
 	pointOfOriginXY, err := ToXYPoint(tms, *tm.PointOfOrigin)
 	if err != nil {
 		panic(fmt.Errorf(`could not get pointOfOrigin coordinates: %w`, err))
@@ -708,9 +982,12 @@ func (tms *TileMatrixSet) FromNative(zoom uint, pt geom.Point) (*slippy.Tile, bo
 		return nil, false
 	}
 
-	return slippy.NewTile(zoom, ux, uy), true
+	return slippy.NewTile(zoom, tm.coalescedCol(ux, uy), uy), true
 }
 
+// ToNative returns the top left point of a tile. In rows with variable matrix widths
+// that is the top left point of the coalesced tile that the tile's column is part of.
+// NB: so the bottom right point of a coalesced tile is not at X+1, but at X+Coalesce.
 func (tms *TileMatrixSet) ToNative(tile *slippy.Tile) (geom.Point, bool) {
 	topLeftPt := geom.Point{}
 	tm, ok := tms.TileMatrices[int(tile.Z)]
@@ -721,6 +998,10 @@ func (tms *TileMatrixSet) ToNative(tile *slippy.Tile) (geom.Point, bool) {
 		// >, not >= because "should be able to take tiles with x and y values 1 higher than the max"
 		return topLeftPt, false
 	}
+	col := tile.X
+	if tile.Y < tm.MatrixHeight {
+		col = tm.coalescedCol(col, tile.Y)
+	}
 
 	pointOfOriginXY, err := ToXYPoint(tms, *tm.PointOfOrigin)
 	if err != nil {
@@ -729,7 +1010,7 @@ func (tms *TileMatrixSet) ToNative(tile *slippy.Tile) (geom.Point, bool) {
 
 	tileSizeX := float64(tm.TileWidth) * tm.CellSize
 	minX := pointOfOriginXY[0]
-	topLeftPt[0] = roundFloat(minX+float64(tile.X)*tileSizeX, CoordPrecision)
+	topLeftPt[0] = roundFloat(minX+float64(col)*tileSizeX, CoordPrecision)
 
 	tileSizeY := float64(tm.TileHeight) * tm.CellSize
 	switch tm.CornerOfOrigin {
@@ -746,12 +1027,10 @@ func (tms *TileMatrixSet) ToNative(tile *slippy.Tile) (geom.Point, bool) {
 	return topLeftPt, true
 }
 
-// MatrixSize returns the width and height of a TileMatrix in native CRS units
+// MatrixSize returns the width and height of a TileMatrix in native CRS units.
+// Variable matrix widths don't matter, coalesced tiles together span the same width.
 func (tms *TileMatrixSet) MatrixSize(tmID TMID) (width float64, height float64) {
 	tm := tms.TileMatrices[tmID]
-	if tm.VariableMatrixWidths != nil {
-		panic("variable matrix widths not supported") // TODO support VariableMatrixWidths
-	}
 	width = roundFloat(float64(tm.MatrixWidth)*float64(tm.TileWidth)*tm.CellSize, CoordPrecision)
 	height = roundFloat(float64(tm.MatrixHeight)*float64(tm.TileHeight)*tm.CellSize, CoordPrecision)
 	return width, height
@@ -790,6 +1069,104 @@ func (tms *TileMatrixSet) MatrixBoundingBox(tmID TMID) (bottomLeft geom.Point, t
 	return bottomLeft, topRight, nil
 }
 
+// TileIndex identifies a tile in a TileMatrixSet.
+// Unlike slippy.Tile it supports negative tile matrix IDs.
+type TileIndex struct {
+	TMID TMID
+	Col  uint
+	Row  uint
+}
+
+// TileBoundingBox returns the bounding box of a tile, in native CRS, enlarged with a buffer (in pixels) on all sides.
+// In rows with variable matrix widths it is the bounding box of the coalesced tile that the column is part of.
+func (tms *TileMatrixSet) TileBoundingBox(tile TileIndex, bufferInPixels uint) (geom.Extent, error) {
+	tm, ok := tms.TileMatrices[tile.TMID]
+	if !ok {
+		return geom.Extent{}, fmt.Errorf(`tile matrix with id %v not found`, tile.TMID)
+	}
+	if tile.Col >= tm.MatrixWidth || tile.Row >= tm.MatrixHeight {
+		return geom.Extent{}, fmt.Errorf(`tile %v is outside tile matrix %v`, tile, tm.ID)
+	}
+	pointOfOriginXY, err := ToXYPoint(tms, *tm.PointOfOrigin)
+	if err != nil {
+		return geom.Extent{}, fmt.Errorf(`could not get pointOfOrigin coordinates: %w`, err)
+	}
+
+	coalesce := tm.Coalesce(tile.Row)
+	tileSizeX := float64(tm.TileWidth) * tm.CellSize
+	tileSizeY := float64(tm.TileHeight) * tm.CellSize
+	// pixels of coalesced tiles are wider
+	bufferX := float64(bufferInPixels) * tm.CellSize * float64(coalesce)
+	bufferY := float64(bufferInPixels) * tm.CellSize
+
+	minX := pointOfOriginXY[0] + float64(tm.coalescedCol(tile.Col, tile.Row))*tileSizeX
+	maxX := minX + float64(coalesce)*tileSizeX
+	var minY, maxY float64
+	switch tm.CornerOfOrigin {
+	default:
+		fallthrough
+	case TopLeft:
+		maxY = pointOfOriginXY[1] - float64(tile.Row)*tileSizeY
+		minY = maxY - tileSizeY
+	case BottomLeft:
+		minY = pointOfOriginXY[1] + float64(tile.Row)*tileSizeY
+		maxY = minY + tileSizeY
+	}
+
+	return geom.Extent{
+		roundFloat(minX-bufferX, CoordPrecision),
+		roundFloat(minY-bufferY, CoordPrecision),
+		roundFloat(maxX+bufferX, CoordPrecision),
+		roundFloat(maxY+bufferY, CoordPrecision),
+	}, nil
+}
+
+// TilesForExtent calls fn for every tile in a tile matrix that intersects the extent (in native CRS), row by row,
+// until fn returns false. Tiles that only touch the extent are left out, unless the extent is a point or a line.
+// In rows with variable matrix widths the coalesced tiles are used, identified by their first column.
+func (tms *TileMatrixSet) TilesForExtent(tmID TMID, extent geom.Extent, fn func(tile TileIndex) bool) error {
+	tm, ok := tms.TileMatrices[tmID]
+	if !ok {
+		return fmt.Errorf(`tile matrix with id %v not found`, tmID)
+	}
+	pointOfOriginXY, err := ToXYPoint(tms, *tm.PointOfOrigin)
+	if err != nil {
+		return fmt.Errorf(`could not get pointOfOrigin coordinates: %w`, err)
+	}
+
+	tileSizeX := float64(tm.TileWidth) * tm.CellSize
+	tileSizeY := float64(tm.TileHeight) * tm.CellSize
+	minCol, maxCol := tileRange(extent.MinX()-pointOfOriginXY[0], extent.MaxX()-pointOfOriginXY[0], tileSizeX)
+	var minRow, maxRow int
+	switch tm.CornerOfOrigin {
+	default:
+		fallthrough
+	case TopLeft:
+		minRow, maxRow = tileRange(pointOfOriginXY[1]-extent.MaxY(), pointOfOriginXY[1]-extent.MinY(), tileSizeY)
+	case BottomLeft:
+		minRow, maxRow = tileRange(extent.MinY()-pointOfOriginXY[1], extent.MaxY()-pointOfOriginXY[1], tileSizeY)
+	}
+	minCol, maxCol = max(minCol, 0), min(maxCol, int(tm.MatrixWidth)-1)
+	minRow, maxRow = max(minRow, 0), min(maxRow, int(tm.MatrixHeight)-1)
+
+	for row := uint(minRow); int(row) <= maxRow; row++ {
+		coalesce := tm.Coalesce(row)
+		for col := tm.coalescedCol(uint(minCol), row); int(col) <= maxCol; col += coalesce {
+			if !fn(TileIndex{TMID: tmID, Col: col, Row: row}) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// tileRange returns the (unclamped) first and last tile that the range from min to max (relative to the origin) intersects
+func tileRange(minOrd, maxOrd, tileSize float64) (first, last int) {
+	first = int(math.Floor(roundFloat(minOrd/tileSize, CoordPrecision)))
+	last = int(math.Ceil(roundFloat(maxOrd/tileSize, CoordPrecision))) - 1
+	return first, max(first, last)
+}
+
 func unmarshalJSONMapUsingUnmarshalJSONFromMap(target marshmallow.UnmarshalerFromJSONMap, data []byte) error {
 	var dataMap map[string]interface{}
 	err := json.Unmarshal(data, &dataMap)
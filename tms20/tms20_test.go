@@ -11,6 +11,7 @@ import (
 	"github.com/go-spatial/geom"
 	"github.com/go-spatial/geom/slippy"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -163,6 +164,24 @@ func TestTileMatrixSet_FromNative(t *testing.T) {
 		{id: "SomethingWithBottomLeftAndLatLonAndDoubleHeight",
 			args: args{0, geom.Point{256.0, 256.0}}, // centroid of extent
 			want: want{ok: true, tile: &slippy.Tile{Z: 0, X: 1, Y: 1}}},
+		{id: "GNOSISGlobalGrid",
+			args: args{2, geom.Point{130.0, 0.0}}, // regular row
+			want: want{ok: true, tile: &slippy.Tile{Z: 2, X: 13, Y: 4}}},
+		{id: "GNOSISGlobalGrid",
+			args: args{2, geom.Point{130.0, 80.0}}, // 4 tiles coalesced
+			want: want{ok: true, tile: &slippy.Tile{Z: 2, X: 12, Y: 0}}},
+		{id: "GNOSISGlobalGrid",
+			args: args{2, geom.Point{130.0, 60.0}}, // 2 tiles coalesced
+			want: want{ok: true, tile: &slippy.Tile{Z: 2, X: 12, Y: 1}}},
+		{id: "GNOSISGlobalGrid",
+			args: args{2, geom.Point{-170.0, -80.0}}, // 4 tiles coalesced, first one
+			want: want{ok: true, tile: &slippy.Tile{Z: 2, X: 0, Y: 7}}},
+		{id: "CDB1GlobalGrid",
+			args: args{0, geom.Point{5.5, 52.3}}, // 2 tiles coalesced
+			want: want{ok: true, tile: &slippy.Tile{Z: 0, X: 184, Y: 37}}},
+		{id: "CDB1GlobalGrid",
+			args: args{0, geom.Point{5.5, 85.5}}, // 6 tiles coalesced
+			want: want{ok: true, tile: &slippy.Tile{Z: 0, X: 180, Y: 4}}},
 	}
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("%v.FromNative(%v, %v)", tt.id, tt.zoom, tt.pt.XY()), func(t *testing.T) {
@@ -198,6 +217,18 @@ func TestTileMatrixSet_ToNative(t *testing.T) {
 		{"SomethingWithBottomLeftAndLatLonAndDoubleHeight",
 			args{&slippy.Tile{Z: 0, X: 1, Y: 1}},
 			want{ok: true, pt: geom.Point{256.0, 512.0}}}, // centroid of extent, top
+		{"GNOSISGlobalGrid",
+			args{&slippy.Tile{Z: 2, X: 13, Y: 4}}, // regular row
+			want{ok: true, pt: geom.Point{112.5, 0.0}}},
+		{"GNOSISGlobalGrid",
+			args{&slippy.Tile{Z: 2, X: 13, Y: 0}}, // 4 tiles coalesced
+			want{ok: true, pt: geom.Point{90.0, 90.0}}},
+		{"GNOSISGlobalGrid",
+			args{&slippy.Tile{Z: 2, X: 16, Y: 8}}, // bottom right of the matrix
+			want{ok: true, pt: geom.Point{180.0, -90.0}}},
+		{"CDB1GlobalGrid",
+			args{&slippy.Tile{Z: 0, X: 185, Y: 37}}, // 2 tiles coalesced
+			want{ok: true, pt: geom.Point{4.0, 53.0}}},
 	}
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("%v.ToNative(%v)", tt.id, tt.tile), func(t *testing.T) {
@@ -214,6 +245,192 @@ func TestTileMatrixSet_ToNative(t *testing.T) {
 	}
 }
 
+func TestTileMatrixSet_MatrixBoundingBox(t *testing.T) {
+	tests := []struct {
+		id             string
+		tmID           TMID
+		wantBottomLeft geom.Point
+		wantTopRight   geom.Point
+		wantErr        bool
+	}{
+		{id: "NetherlandsRDNewQuad", tmID: 0, wantBottomLeft: geom.Point{-285401.92, 22598.08}, wantTopRight: geom.Point{595401.92, 903401.92}},
+		{id: "SomethingWithBottomLeftAndLatLonAndDoubleHeight", tmID: 0, wantBottomLeft: geom.Point{0.0, 0.0}, wantTopRight: geom.Point{512.0, 1024.0}},
+		{id: "GNOSISGlobalGrid", tmID: 2, wantBottomLeft: geom.Point{-180.0, -90.0}, wantTopRight: geom.Point{180.0, 90.0}},
+		{id: "CDB1GlobalGrid", tmID: -10, wantBottomLeft: geom.Point{-180.0, -90.0}, wantTopRight: geom.Point{180.0, 90.0}},
+		{id: "CDB1GlobalGrid", tmID: 0, wantBottomLeft: geom.Point{-180.0, -90.0}, wantTopRight: geom.Point{180.0, 90.0}},
+		{id: "CDB1GlobalGrid", tmID: 99, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v.MatrixBoundingBox(%v)", tt.id, tt.tmID), func(t *testing.T) {
+			tms, err := loadTestOrEmbeddedTileMatrix(tt.id)
+			require.NoError(t, err)
+			bottomLeft, topRight, err := tms.MatrixBoundingBox(tt.tmID)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBottomLeft, bottomLeft)
+			require.Equal(t, tt.wantTopRight, topRight)
+		})
+	}
+}
+
+func TestTileMatrixSet_TileBoundingBox(t *testing.T) {
+	tests := []struct {
+		id      string
+		tile    TileIndex
+		buffer  uint
+		want    geom.Extent
+		wantErr bool
+	}{
+		{id: "NetherlandsRDNewQuad", tile: TileIndex{TMID: 1, Col: 1, Row: 1},
+			want: geom.Extent{155000.0, 22598.08, 595401.92, 463000.0}},
+		{id: "NetherlandsRDNewQuad", tile: TileIndex{TMID: 1, Col: 0, Row: 0},
+			want: geom.Extent{-285401.92, 463000.0, 155000.0, 903401.92}},
+		{id: "NetherlandsRDNewQuad", tile: TileIndex{TMID: 1, Col: 1, Row: 1}, buffer: 16, // 16 * 1720.32
+			want: geom.Extent{155000.0 - 27525.12, 22598.08 - 27525.12, 595401.92 + 27525.12, 463000.0 + 27525.12}},
+		{id: "SomethingWithBottomLeftAndLatLonAndDoubleHeight", tile: TileIndex{TMID: 0, Col: 1, Row: 1},
+			want: geom.Extent{256.0, 256.0, 512.0, 512.0}},
+		{id: "SomethingWithBottomLeftAndLatLonAndDoubleHeight", tile: TileIndex{TMID: 0, Col: 0, Row: 3}, buffer: 1,
+			want: geom.Extent{-1.0, 767.0, 257.0, 1025.0}},
+		{id: "GNOSISGlobalGrid", tile: TileIndex{TMID: 2, Col: 13, Row: 0}, // 4 tiles coalesced
+			want: geom.Extent{90.0, 67.5, 180.0, 90.0}},
+		{id: "CDB1GlobalGrid", tile: TileIndex{TMID: -10, Col: 7, Row: 179}, // 12 tiles coalesced
+			want: geom.Extent{-180.0, -90.0, -168.0, -89.0}},
+		{id: "NetherlandsRDNewQuad", tile: TileIndex{TMID: 1, Col: 2, Row: 0}, wantErr: true},
+		{id: "NetherlandsRDNewQuad", tile: TileIndex{TMID: 99}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v.TileBoundingBox(%v, %v)", tt.id, tt.tile, tt.buffer), func(t *testing.T) {
+			tms, err := loadTestOrEmbeddedTileMatrix(tt.id)
+			require.NoError(t, err)
+			got, err := tms.TileBoundingBox(tt.tile, tt.buffer)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.InDeltaSlice(t, tt.want[:], got[:], 1e-6)
+		})
+	}
+}
+
+func TestTileMatrixSet_TilesForExtent(t *testing.T) {
+	tests := []struct {
+		id     string
+		tmID   TMID
+		extent geom.Extent
+		want   []TileIndex
+	}{
+		{id: "NetherlandsRDNewQuad", tmID: 1, extent: geom.Extent{150000.0, 460000.0, 160000.0, 470000.0},
+			want: []TileIndex{{1, 0, 0}, {1, 1, 0}, {1, 0, 1}, {1, 1, 1}}},
+		{id: "NetherlandsRDNewQuad", tmID: 1, extent: geom.Extent{155000.0, 22598.08, 595401.92, 463000.0}, // exactly a tile
+			want: []TileIndex{{1, 1, 1}}},
+		{id: "NetherlandsRDNewQuad", tmID: 1, extent: geom.Extent{155000.0, 463000.0, 155000.0, 463000.0}, // point on a corner, same as FromNative
+			want: []TileIndex{{1, 1, 1}}},
+		{id: "NetherlandsRDNewQuad", tmID: 1, extent: geom.Extent{-1000000.0, 0.0, 1000000.0, 1000000.0}, // larger than the matrix
+			want: []TileIndex{{1, 0, 0}, {1, 1, 0}, {1, 0, 1}, {1, 1, 1}}},
+		{id: "NetherlandsRDNewQuad", tmID: 1, extent: geom.Extent{-1000000.0, 0.0, -900000.0, 100000.0}, // outside
+			want: nil},
+		{id: "SomethingWithBottomLeftAndLatLonAndDoubleHeight", tmID: 0, extent: geom.Extent{100.0, 300.0, 300.0, 800.0},
+			want: []TileIndex{{0, 0, 1}, {0, 1, 1}, {0, 0, 2}, {0, 1, 2}, {0, 0, 3}, {0, 1, 3}}},
+		{id: "GNOSISGlobalGrid", tmID: 2, extent: geom.Extent{100.0, 60.0, 140.0, 80.0}, // coalesced rows
+			want: []TileIndex{{2, 12, 0}, {2, 12, 1}, {2, 14, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v.TilesForExtent(%v, %v)", tt.id, tt.tmID, tt.extent), func(t *testing.T) {
+			tms, err := loadTestOrEmbeddedTileMatrix(tt.id)
+			require.NoError(t, err)
+			var got []TileIndex
+			err = tms.TilesForExtent(tt.tmID, tt.extent, func(tile TileIndex) bool {
+				got = append(got, tile)
+				return true
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTileMatrix_Coalesce(t *testing.T) {
+	tms, err := LoadEmbeddedTileMatrixSet("GNOSISGlobalGrid")
+	require.NoError(t, err)
+	tm := tms.TileMatrices[2]
+	for row, want := range []uint{4, 2, 1, 1, 1, 1, 2, 4} {
+		require.Equalf(t, want, tm.Coalesce(uint(row)), "Coalesce(%d)", row)
+	}
+}
+
+func TestTileMatrixSet_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		mutate      func(tms *TileMatrixSet)
+		wantErrPart string
+	}{
+		{name: "valid", mutate: func(_ *TileMatrixSet) {}},
+		{name: "struct tag", wantErrPart: "tile matrix 5: Key: 'TileMatrix.TileWidth'", mutate: func(tms *TileMatrixSet) {
+			tm := tms.TileMatrices[5]
+			tm.TileWidth = 0
+			tms.TileMatrices[5] = tm
+		}},
+		{name: "cell size not consistent with scale denominator", wantErrPart: "tile matrix 3: cell size", mutate: func(tms *TileMatrixSet) {
+			tm := tms.TileMatrices[3]
+			tm.CellSize *= 1.01
+			tms.TileMatrices[3] = tm
+		}},
+		{name: "cell size of the top tile matrix not following from scale denominator", wantErrPart: "tile matrix 0: cell size", mutate: func(tms *TileMatrixSet) {
+			tm := tms.TileMatrices[0]
+			tm.CellSize *= 1.01
+			tms.TileMatrices[0] = tm
+		}},
+		{name: "cell sizes consistently not following from scale denominators", wantErrPart: "tile matrix 0: cell size", mutate: func(tms *TileMatrixSet) {
+			for tmID, tm := range tms.TileMatrices {
+				tm.CellSize *= 1.01
+				tms.TileMatrices[tmID] = tm
+			}
+		}},
+		{name: "variable matrix width outside matrix", wantErrPart: "tile matrix 2: variable matrix width 0: rows 3 to 4", mutate: func(tms *TileMatrixSet) {
+			tm := tms.TileMatrices[2]
+			tm.VariableMatrixWidths = []VariableMatrixWidth{{Coalesce: 2, MinTileRow: 3, MaxTileRow: 4}}
+			tms.TileMatrices[2] = tm
+		}},
+		{name: "variable matrix width coalesce not dividing width", wantErrPart: "tile matrix 2: variable matrix width 0: matrix width 4 is not a multiple of coalesce 3", mutate: func(tms *TileMatrixSet) {
+			tm := tms.TileMatrices[2]
+			tm.VariableMatrixWidths = []VariableMatrixWidth{{Coalesce: 3, MinTileRow: 0, MaxTileRow: 0}}
+			tms.TileMatrices[2] = tm
+		}},
+		{name: "variable matrix widths overlap", wantErrPart: "tile matrix 2: variable matrix width 1: rows overlap with variable matrix width 0", mutate: func(tms *TileMatrixSet) {
+			tm := tms.TileMatrices[2]
+			tm.VariableMatrixWidths = []VariableMatrixWidth{{Coalesce: 2, MinTileRow: 0, MaxTileRow: 1}, {Coalesce: 4, MinTileRow: 1, MaxTileRow: 1}}
+			tms.TileMatrices[2] = tm
+		}},
+		{name: "bounding box outside matrix", wantErrPart: "tile matrix 0: bounding box", mutate: func(tms *TileMatrixSet) {
+			tms.BoundingBox.UpperRight[0] += 1000
+		}},
+		{name: "unknown axis order", wantErrPart: "could not determine the axis order of the crs", mutate: func(tms *TileMatrixSet) {
+			tms.CRS = &URICRS{uri: "http://www.opengis.net/def/crs/CUSTOM/0/1", authority: "CUSTOM", code: "1"}
+			tms.OrderedAxes = nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// unmarshal again (instead of using LoadEmbeddedTileMatrixSet) to not mutate the cached one
+			rawJSON, err := embeddedTileMatrixSetsJSONFS.ReadFile("tilematrixsets/NetherlandsRDNewQuad" + extJSON)
+			require.NoError(t, err)
+			var tms TileMatrixSet
+			require.NoError(t, json.Unmarshal(rawJSON, &tms))
+			tt.mutate(&tms)
+			err = tms.Validate()
+			if tt.wantErrPart == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.wantErrPart)
+			}
+		})
+	}
+}
+
 func loadTestOrEmbeddedTileMatrix(id string) (TileMatrixSet, error) {
 	p, err := filepath.Abs(path.Join("testdata", id+extJSON))
 	if err != nil {
@@ -225,3 +442,42 @@ func loadTestOrEmbeddedTileMatrix(id string) (TileMatrixSet, error) {
 	}
 	return tms, err
 }
+
+func TestTileMatrixSet_TileMatrixIDsByScaleDenominator(t *testing.T) {
+	tms, err := LoadEmbeddedTileMatrixSet("NetherlandsRDNewQuad")
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		min, max float64
+		want     []TMID
+	}{
+		{name: "inclusive", min: 96000, max: 768000, want: []TMID{4, 5, 6, 7}},
+		{name: "between", min: 100000, max: 700000, want: []TMID{5, 6}},
+		{name: "no upper bound", min: 3072000, want: []TMID{0, 1, 2}},
+		{name: "none", min: 100000, max: 110000, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tms.TileMatrixIDsByScaleDenominator(tt.min, tt.max))
+		})
+	}
+}
+
+func TestTileMatrixSet_TileMatrixIDsByCellSize(t *testing.T) {
+	tms, err := LoadEmbeddedTileMatrixSet("NetherlandsRDNewQuad")
+	require.NoError(t, err)
+	assert.Equal(t, []TMID{12, 13, 14}, tms.TileMatrixIDsByCellSize(0.2, 0.84))
+	assert.Equal(t, []TMID{0}, tms.TileMatrixIDsByCellSize(3440.64, 0))
+}
+
+func TestTileMatrixSet_AuthorityCode(t *testing.T) {
+	for tmsID, want := range map[string]string{
+		"NetherlandsRDNewQuad": "EPSG:28992",
+		"WebMercatorQuad":      "EPSG:3857",
+		"WorldCRS84Quad":       "OGC:CRS84",
+	} {
+		tms, err := LoadEmbeddedTileMatrixSet(tmsID)
+		require.NoError(t, err)
+		assert.Equal(t, want, tms.AuthorityCode(), tmsID)
+	}
+}
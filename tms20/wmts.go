@@ -0,0 +1,303 @@
+package tms20
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	wmtsNamespace = "http://www.opengis.net/wmts/1.0"
+	owsNamespace  = "http://www.opengis.net/ows/1.1"
+	// meters per degree on the equator of the WGS 84 ellipsoid, as used by WMTS 1.0 (Annex E)
+	metersPerDegree = 6378137 * 2 * math.Pi / 360
+)
+
+var (
+	// geographic CRSs (in degrees) that can be encountered in WMTS capabilities
+	epsgCodesInDegrees = map[string]bool{"4326": true, "4258": true, "4269": true, "4283": true, "4167": true, "4979": true, "CRS84": true}
+)
+
+// wmtsCapabilities is (the tile matrix set part of) a WMTS 1.0 capabilities document, for unmarshalling
+type wmtsCapabilities struct {
+	XMLName        xml.Name            `xml:"Capabilities"`
+	TileMatrixSets []wmtsTileMatrixSet `xml:"Contents>TileMatrixSet"`
+}
+
+// wmtsTileMatrixSet is a WMTS 1.0 TileMatrixSet element, for unmarshalling (regardless of namespace prefixes)
+type wmtsTileMatrixSet struct {
+	XMLName      xml.Name `xml:"TileMatrixSet"`
+	Identifier   string   `xml:"Identifier"`
+	Title        string   `xml:"Title"`
+	SupportedCRS string   `xml:"SupportedCRS"`
+	BoundingBox  *struct {
+		CRS         string `xml:"crs,attr"`
+		LowerCorner string `xml:"LowerCorner"`
+		UpperCorner string `xml:"UpperCorner"`
+	} `xml:"BoundingBox"`
+	WellKnownScaleSet string `xml:"WellKnownScaleSet"`
+	TileMatrices      []struct {
+		Identifier       string  `xml:"Identifier"`
+		ScaleDenominator float64 `xml:"ScaleDenominator"`
+		TopLeftCorner    string  `xml:"TopLeftCorner"`
+		TileWidth        uint    `xml:"TileWidth"`
+		TileHeight       uint    `xml:"TileHeight"`
+		MatrixWidth      uint    `xml:"MatrixWidth"`
+		MatrixHeight     uint    `xml:"MatrixHeight"`
+	} `xml:"TileMatrix"`
+}
+
+// wmtsTileMatrixSetOut is a WMTS 1.0 TileMatrixSet element, for marshalling with namespace prefixes
+type wmtsTileMatrixSetOut struct {
+	XMLName           xml.Name            `xml:"TileMatrixSet"`
+	XMLNS             string              `xml:"xmlns,attr"`
+	XMLNSOWS          string              `xml:"xmlns:ows,attr"`
+	Identifier        string              `xml:"ows:Identifier"`
+	Title             string              `xml:"ows:Title,omitempty"`
+	BoundingBox       *wmtsBoundingBoxOut `xml:"ows:BoundingBox,omitempty"`
+	SupportedCRS      string              `xml:"ows:SupportedCRS"`
+	WellKnownScaleSet string              `xml:"WellKnownScaleSet,omitempty"`
+	TileMatrices      []wmtsTileMatrixOut `xml:"TileMatrix"`
+}
+
+type wmtsBoundingBoxOut struct {
+	CRS         string `xml:"crs,attr"`
+	LowerCorner string `xml:"ows:LowerCorner"`
+	UpperCorner string `xml:"ows:UpperCorner"`
+}
+
+type wmtsTileMatrixOut struct {
+	Identifier       string `xml:"ows:Identifier"`
+	ScaleDenominator string `xml:"ScaleDenominator"`
+	TopLeftCorner    string `xml:"TopLeftCorner"`
+	TileWidth        uint   `xml:"TileWidth"`
+	TileHeight       uint   `xml:"TileHeight"`
+	MatrixWidth      uint   `xml:"MatrixWidth"`
+	MatrixHeight     uint   `xml:"MatrixHeight"`
+}
+
+// LoadWMTSTileMatrixSet loads a tile matrix set from a WMTS 1.0 capabilities document or TileMatrixSet fragment.
+// The id (WMTS identifier) can be empty if the document contains only one tile matrix set.
+func LoadWMTSTileMatrixSet(path string, id string) (TileMatrixSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TileMatrixSet{}, err
+	}
+	tileMatrixSets, err := UnmarshalWMTSTileMatrixSets(data)
+	if err != nil {
+		return TileMatrixSet{}, err
+	}
+	if id == "" {
+		if len(tileMatrixSets) != 1 {
+			return TileMatrixSet{}, fmt.Errorf(`%s contains %d tile matrix sets, an id is needed`, path, len(tileMatrixSets))
+		}
+		return tileMatrixSets[0], nil
+	}
+	for _, tms := range tileMatrixSets {
+		if tms.ID == id {
+			return tms, nil
+		}
+	}
+	return TileMatrixSet{}, fmt.Errorf(`tile matrix set %s not found in %s`, id, path)
+}
+
+// UnmarshalWMTSTileMatrixSets converts the TileMatrixSet elements in a WMTS 1.0 capabilities document
+// (or a single TileMatrixSet element) to tile matrix sets.
+// The cell sizes are derived from the scale denominators, so only CRSs in meters or degrees are supported.
+// The tile matrices keep their identifiers, and are numbered by the integers those end in (e.g. 5 or EPSG:28992:5)
+// if they all do, otherwise in document order (see numberTileMatrices).
+func UnmarshalWMTSTileMatrixSets(data []byte) ([]TileMatrixSet, error) {
+	var wmtsTileMatrixSets []wmtsTileMatrixSet
+	var capabilities wmtsCapabilities
+	if err := xml.Unmarshal(data, &capabilities); err == nil {
+		wmtsTileMatrixSets = capabilities.TileMatrixSets
+	} else {
+		var fragment wmtsTileMatrixSet
+		if err2 := xml.Unmarshal(data, &fragment); err2 != nil {
+			return nil, fmt.Errorf(`neither a WMTS capabilities document (%v) nor a TileMatrixSet (%w)`, err, err2)
+		}
+		wmtsTileMatrixSets = []wmtsTileMatrixSet{fragment}
+	}
+	if len(wmtsTileMatrixSets) == 0 {
+		return nil, errors.New(`no tile matrix sets found`)
+	}
+	tileMatrixSets := make([]TileMatrixSet, 0, len(wmtsTileMatrixSets))
+	for _, wmtsTMS := range wmtsTileMatrixSets {
+		tms, err := wmtsTMS.toTileMatrixSet()
+		if err != nil {
+			return nil, fmt.Errorf(`tile matrix set %s: %w`, wmtsTMS.Identifier, err)
+		}
+		tileMatrixSets = append(tileMatrixSets, tms)
+	}
+	return tileMatrixSets, nil
+}
+
+func (wmtsTMS *wmtsTileMatrixSet) toTileMatrixSet() (TileMatrixSet, error) {
+	crs, err := wmtsCRS(wmtsTMS.SupportedCRS)
+	if err != nil {
+		return TileMatrixSet{}, err
+	}
+	metersPerUnit := 1.0
+	if epsgCodesInDegrees[crs.Code()] {
+		metersPerUnit = metersPerDegree
+	}
+	tms := TileMatrixSet{
+		ID:                wmtsTMS.Identifier,
+		Title:             wmtsTMS.Title,
+		CRS:               crs,
+		WellKnownScaleSet: wmtsTMS.WellKnownScaleSet,
+		TileMatrices:      make(map[TMID]TileMatrix, len(wmtsTMS.TileMatrices)),
+	}
+	if bbox := wmtsTMS.BoundingBox; bbox != nil {
+		lowerLeft, err := parseWMTSCorner(bbox.LowerCorner)
+		if err != nil {
+			return tms, err
+		}
+		upperRight, err := parseWMTSCorner(bbox.UpperCorner)
+		if err != nil {
+			return tms, err
+		}
+		bboxCRS := crs
+		if bbox.CRS != "" {
+			if bboxCRS, err = wmtsCRS(bbox.CRS); err != nil {
+				return tms, err
+			}
+		}
+		tms.BoundingBox = &TwoDBoundingBox{LowerLeft: &lowerLeft, UpperRight: &upperRight, CRS: bboxCRS}
+	}
+	identifiers := make([]string, 0, len(wmtsTMS.TileMatrices))
+	for _, wmtsTM := range wmtsTMS.TileMatrices {
+		identifiers = append(identifiers, wmtsTM.Identifier)
+	}
+	tmIDs, err := numberTileMatrices(identifiers)
+	if err != nil {
+		return tms, err
+	}
+	for i, wmtsTM := range wmtsTMS.TileMatrices {
+		topLeftCorner, err := parseWMTSCorner(wmtsTM.TopLeftCorner)
+		if err != nil {
+			return tms, fmt.Errorf(`tile matrix %s: %w`, wmtsTM.Identifier, err)
+		}
+		tms.TileMatrices[tmIDs[i]] = TileMatrix{
+			ID:               wmtsTM.Identifier,
+			ScaleDenominator: wmtsTM.ScaleDenominator,
+			CellSize:         wmtsTM.ScaleDenominator * StandardizedRenderingPixelSize / metersPerUnit,
+			CornerOfOrigin:   TopLeft,
+			PointOfOrigin:    &topLeftCorner,
+			TileWidth:        wmtsTM.TileWidth,
+			TileHeight:       wmtsTM.TileHeight,
+			MatrixWidth:      wmtsTM.MatrixWidth,
+			MatrixHeight:     wmtsTM.MatrixHeight,
+		}
+	}
+	if err = tms.Validate(); err != nil {
+		return tms, err
+	}
+	return tms, nil
+}
+
+// wmtsCRS parses a CRS URN (e.g. urn:ogc:def:crs:EPSG::28992) or EPSG:28992 into an URI CRS
+func wmtsCRS(s string) (CRS, error) {
+	s = strings.TrimSpace(s)
+	if code, ok := strings.CutPrefix(s, "EPSG:"); ok {
+		s = "urn:ogc:def:crs:EPSG::" + code
+	}
+	uriParts := crsURIRegexURN.FindStringSubmatch(s)
+	if uriParts == nil {
+		return unmarshalCRS(s)
+	}
+	version := uriParts[2]
+	if version == "" {
+		version = "0"
+	}
+	return unmarshalCRS(fmt.Sprintf("http://www.opengis.net/def/crs/%s/%s/%s", uriParts[1], version, uriParts[3]))
+}
+
+// wmtsCRSURN formats a CRS as an URN (e.g. urn:ogc:def:crs:EPSG::28992), as used in WMTS 1.0
+func wmtsCRSURN(crs CRS) (string, error) {
+	if _, ok := crs.(*ReferenceSystemCRS); ok || crs.Authority() == "" || crs.Code() == "" {
+		return "", errors.New(`only crss with an authority and code are supported in WMTS`)
+	}
+	version := crs.Version()
+	if version == "0" {
+		version = ""
+	}
+	return fmt.Sprintf("urn:ogc:def:crs:%s:%s:%s", crs.Authority(), version, crs.Code()), nil
+}
+
+func parseWMTSCorner(s string) (TwoDPoint, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return TwoDPoint{}, fmt.Errorf(`corner "%s" should have 2 coordinates`, s)
+	}
+	var point TwoDPoint
+	for i, field := range fields {
+		ord, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return point, fmt.Errorf(`corner "%s": %w`, s, err)
+		}
+		point[i] = ord
+	}
+	return point, nil
+}
+
+func formatWMTSCorner(point TwoDPoint) string {
+	return strconv.FormatFloat(point[0], 'f', -1, 64) + " " + strconv.FormatFloat(point[1], 'f', -1, 64)
+}
+
+// MarshalWMTSTileMatrixSet converts a tile matrix set to a WMTS 1.0 TileMatrixSet element.
+// WMTS 1.0 has no bottom left corner of origin nor variable matrix widths, so those are refused.
+func MarshalWMTSTileMatrixSet(tms *TileMatrixSet) ([]byte, error) {
+	supportedCRS, err := wmtsCRSURN(tms.CRS)
+	if err != nil {
+		return nil, err
+	}
+	out := wmtsTileMatrixSetOut{
+		XMLNS:             wmtsNamespace,
+		XMLNSOWS:          owsNamespace,
+		Identifier:        tms.ID,
+		Title:             tms.Title,
+		SupportedCRS:      supportedCRS,
+		WellKnownScaleSet: tms.WellKnownScaleSet,
+	}
+	if bbox := tms.BoundingBox; bbox != nil {
+		bboxCRS, err := wmtsCRSURN(bbox.CRS)
+		if err != nil {
+			return nil, err
+		}
+		out.BoundingBox = &wmtsBoundingBoxOut{
+			CRS:         bboxCRS,
+			LowerCorner: formatWMTSCorner(*bbox.LowerLeft),
+			UpperCorner: formatWMTSCorner(*bbox.UpperRight),
+		}
+	}
+	tmIDs := make([]TMID, 0, len(tms.TileMatrices))
+	for tmID := range tms.TileMatrices {
+		tmIDs = append(tmIDs, tmID)
+	}
+	sort.Ints(tmIDs)
+	for _, tmID := range tmIDs {
+		tm := tms.TileMatrices[tmID]
+		if tm.CornerOfOrigin == BottomLeft {
+			return nil, fmt.Errorf(`tile matrix %s: a bottom left corner of origin is not supported in WMTS`, tm.ID)
+		}
+		if len(tm.VariableMatrixWidths) > 0 {
+			return nil, fmt.Errorf(`tile matrix %s: variable matrix widths are not supported in WMTS`, tm.ID)
+		}
+		out.TileMatrices = append(out.TileMatrices, wmtsTileMatrixOut{
+			Identifier:       tm.ID,
+			ScaleDenominator: strconv.FormatFloat(tm.ScaleDenominator, 'f', -1, 64),
+			TopLeftCorner:    formatWMTSCorner(*tm.PointOfOrigin),
+			TileWidth:        tm.TileWidth,
+			TileHeight:       tm.TileHeight,
+			MatrixWidth:      tm.MatrixWidth,
+			MatrixHeight:     tm.MatrixHeight,
+		})
+	}
+	return xml.MarshalIndent(out, "", "  ")
+}
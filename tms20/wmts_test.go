@@ -0,0 +1,151 @@
+package tms20
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWMTSTileMatrixSet(t *testing.T) {
+	tests := []struct {
+		name          string
+		id            string
+		wantSRID      uint
+		wantTMCount   int
+		wantCellSize0 float64
+		wantOrigin    TwoDPoint
+		wantTM0ID     string
+		wantErr       string
+	}{
+		{
+			name:          "RD",
+			id:            "EPSG:28992",
+			wantSRID:      28992,
+			wantTMCount:   3,
+			wantCellSize0: 3440.64,
+			wantOrigin:    TwoDPoint{-285401.92, 903401.92},
+			wantTM0ID:     "EPSG:28992:0",
+		}, {
+			name:          "WGS84 with non-integer ids",
+			id:            "WGS84",
+			wantSRID:      4326,
+			wantTMCount:   2,
+			wantCellSize0: 180.0 / 256,
+			wantOrigin:    TwoDPoint{90, -180},
+			wantTM0ID:     "top",
+		}, {
+			name:    "no id",
+			wantErr: "contains 2 tile matrix sets, an id is needed",
+		}, {
+			name:    "unknown id",
+			id:      "foo",
+			wantErr: "tile matrix set foo not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tms, err := LoadWMTSTileMatrixSet("testdata/wmts_capabilities.xml", tt.id)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, tms.ID)
+			assert.Equal(t, tt.wantSRID, tms.SRID())
+			require.Len(t, tms.TileMatrices, tt.wantTMCount)
+			assert.InDelta(t, tt.wantCellSize0, tms.TileMatrices[0].CellSize, 1e-9)
+			assert.Equal(t, tt.wantOrigin, *tms.TileMatrices[0].PointOfOrigin)
+			assert.Equal(t, tt.wantTM0ID, tms.TileMatrices[0].ID)
+		})
+	}
+}
+
+func TestNumberTileMatrices(t *testing.T) {
+	tests := []struct {
+		name        string
+		identifiers []string
+		want        []TMID
+		wantErr     string
+	}{
+		{name: "integers", identifiers: []string{"0", "1", "2"}, want: []TMID{0, 1, 2}},
+		{name: "ending in integers", identifiers: []string{"EPSG:28992:3", "EPSG:28992:4"}, want: []TMID{3, 4}},
+		{name: "not in order", identifiers: []string{"2", "0", "1"}, want: []TMID{2, 0, 1}},
+		{name: "not integers", identifiers: []string{"top", "next"}, want: []TMID{0, 1}},
+		{name: "not all integers", identifiers: []string{"2", "3", "next"}, want: []TMID{0, 1, 2}},
+		{name: "duplicate identifiers", identifiers: []string{"top", "top"}, wantErr: "duplicate tile matrix top"},
+		{name: "ending in the same integer", identifiers: []string{"a:1", "b:1"}, wantErr: "tile matrices a:1 and b:1 both end in 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := numberTileMatrices(tt.identifiers)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadTileMatrixSet(t *testing.T) {
+	for _, idOrPath := range []string{"NetherlandsRDNewQuad", "testdata/wmts_capabilities.xml#EPSG:28992"} {
+		tms, err := LoadTileMatrixSet(idOrPath)
+		require.NoError(t, err, idOrPath)
+		assert.Equal(t, uint(28992), tms.SRID())
+	}
+	_, err := LoadTileMatrixSet("testdata/wmts_capabilities.xml")
+	assert.ErrorContains(t, err, "an id is needed")
+}
+
+func TestMarshalWMTSTileMatrixSet(t *testing.T) {
+	for _, tmsID := range []string{"NetherlandsRDNewQuad", "WebMercatorQuad", "WorldCRS84Quad"} {
+		t.Run(tmsID, func(t *testing.T) {
+			tms, err := LoadEmbeddedTileMatrixSet(tmsID)
+			require.NoError(t, err)
+			wmtsXML, err := MarshalWMTSTileMatrixSet(&tms)
+			require.NoError(t, err)
+			tileMatrixSets, err := UnmarshalWMTSTileMatrixSets(wmtsXML)
+			require.NoError(t, err)
+			require.Len(t, tileMatrixSets, 1)
+			roundTripped := tileMatrixSets[0]
+			assert.Equal(t, tms.ID, roundTripped.ID)
+			assert.Equal(t, tms.CRS.Code(), roundTripped.CRS.Code())
+			require.Len(t, roundTripped.TileMatrices, len(tms.TileMatrices))
+			for tmID, tm := range tms.TileMatrices {
+				roundTrippedTM := roundTripped.TileMatrices[tmID]
+				assert.Equal(t, tm.ID, roundTrippedTM.ID, tmID)
+				assert.InEpsilon(t, tm.CellSize, roundTrippedTM.CellSize, 1e-6, tmID)
+				assert.Equal(t, *tm.PointOfOrigin, *roundTrippedTM.PointOfOrigin, tmID)
+				assert.Equal(t, tm.MatrixWidth, roundTrippedTM.MatrixWidth, tmID)
+			}
+		})
+	}
+
+	for _, id := range []string{"EPSG:28992", "WGS84"} {
+		t.Run(id, func(t *testing.T) {
+			tms, err := LoadWMTSTileMatrixSet("testdata/wmts_capabilities.xml", id)
+			require.NoError(t, err)
+			wmtsXML, err := MarshalWMTSTileMatrixSet(&tms)
+			require.NoError(t, err)
+			tileMatrixSets, err := UnmarshalWMTSTileMatrixSets(wmtsXML)
+			require.NoError(t, err)
+			require.Len(t, tileMatrixSets, 1)
+			assert.Equal(t, tms.TileMatrices, tileMatrixSets[0].TileMatrices)
+
+			// and as JSON, e.g. in a reproduction
+			tmsJSON, err := json.Marshal(&tms)
+			require.NoError(t, err)
+			var fromJSON TileMatrixSet
+			require.NoError(t, json.Unmarshal(tmsJSON, &fromJSON))
+			assert.Equal(t, tms.TileMatrices, fromJSON.TileMatrices)
+		})
+	}
+
+	gnosis, err := LoadEmbeddedTileMatrixSet("GNOSISGlobalGrid")
+	require.NoError(t, err)
+	_, err = MarshalWMTSTileMatrixSet(&gnosis)
+	assert.ErrorContains(t, err, "variable matrix widths are not supported")
+}
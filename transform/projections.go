@@ -0,0 +1,215 @@
+package transform
+
+import (
+	"math"
+)
+
+const (
+	deg2rad = math.Pi / 180
+	rad2deg = 180 / math.Pi
+)
+
+// ellipsoid is defined by its semi-major axis and inverse flattening
+type ellipsoid struct {
+	a    float64
+	invF float64
+}
+
+var (
+	wgs84 = ellipsoid{a: 6378137, invF: 298.257223563}
+	grs80 = ellipsoid{a: 6378137, invF: 298.257222101}
+)
+
+func (el ellipsoid) f() float64 {
+	return 1 / el.invF
+}
+
+// e returns the (first) eccentricity
+func (el ellipsoid) e() float64 {
+	f := el.f()
+	return math.Sqrt(2*f - f*f)
+}
+
+// projection converts between projected coordinates and geographic ones (longitude, latitude in degrees)
+type projection interface {
+	toGeographic(x, y float64) (lon, lat float64)
+	fromGeographic(lon, lat float64) (x, y float64)
+}
+
+// geographic is a "projection" of longitude, latitude in degrees (in x, y order, as in a GPKG)
+type geographic struct{}
+
+func (geographic) toGeographic(x, y float64) (float64, float64) {
+	return x, y
+}
+
+func (geographic) fromGeographic(lon, lat float64) (float64, float64) {
+	return lon, lat
+}
+
+// webMercator is the spherical (pseudo) mercator projection of EPSG:3857
+type webMercator struct{}
+
+// maxWebMercatorLatitude is where the web mercator world becomes square
+const maxWebMercatorLatitude = 85.0511287798066
+
+func (webMercator) toGeographic(x, y float64) (float64, float64) {
+	lon := x / wgs84.a * rad2deg
+	lat := (2*math.Atan(math.Exp(y/wgs84.a)) - math.Pi/2) * rad2deg
+	return lon, lat
+}
+
+func (webMercator) fromGeographic(lon, lat float64) (float64, float64) {
+	lat = math.Max(-maxWebMercatorLatitude, math.Min(maxWebMercatorLatitude, lat))
+	x := wgs84.a * lon * deg2rad
+	y := wgs84.a * math.Log(math.Tan(math.Pi/4+lat*deg2rad/2))
+	return x, y
+}
+
+// mercator is the ellipsoidal mercator projection (variant A, on the equator) of EPSG:3395
+type mercator struct {
+	el ellipsoid
+}
+
+func (p mercator) toGeographic(x, y float64) (float64, float64) {
+	e := p.el.e()
+	t := math.Exp(-y / p.el.a)
+	// iterate the conformal latitude to the geodetic one
+	lat := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		eSinLat := e * math.Sin(lat)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-eSinLat)/(1+eSinLat), e/2))
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+	return x / p.el.a * rad2deg, lat * rad2deg
+}
+
+func (p mercator) fromGeographic(lon, lat float64) (float64, float64) {
+	e := p.el.e()
+	phi := math.Max(-maxWebMercatorLatitude, math.Min(maxWebMercatorLatitude, lat)) * deg2rad
+	eSinPhi := e * math.Sin(phi)
+	x := p.el.a * lon * deg2rad
+	y := p.el.a * math.Log(math.Tan(math.Pi/4+phi/2)*math.Pow((1-eSinPhi)/(1+eSinPhi), e/2))
+	return x, y
+}
+
+// lambertAzimuthalEqualArea is the ellipsoidal (oblique) LAEA projection, EPSG method 9820
+type lambertAzimuthalEqualArea struct {
+	el                          ellipsoid
+	lon0, lat0                  float64 // degrees
+	falseEasting, falseNorthing float64
+}
+
+func (p lambertAzimuthalEqualArea) q(phi float64) float64 {
+	e := p.el.e()
+	e2 := e * e
+	sinPhi := math.Sin(phi)
+	return (1 - e2) * (sinPhi/(1-e2*sinPhi*sinPhi) - 1/(2*e)*math.Log((1-e*sinPhi)/(1+e*sinPhi)))
+}
+
+// constants returns qP, beta0, Rq and D as in the EPSG guidance note 7-2
+func (p lambertAzimuthalEqualArea) constants() (qP, beta0, rq, d float64) {
+	e := p.el.e()
+	phi0 := p.lat0 * deg2rad
+	qP = p.q(math.Pi / 2)
+	beta0 = math.Asin(p.q(phi0) / qP)
+	rq = p.el.a * math.Sqrt(qP/2)
+	d = p.el.a * (math.Cos(phi0) / math.Sqrt(1-e*e*math.Sin(phi0)*math.Sin(phi0))) / (rq * math.Cos(beta0))
+	return
+}
+
+func (p lambertAzimuthalEqualArea) fromGeographic(lon, lat float64) (float64, float64) {
+	qP, beta0, rq, d := p.constants()
+	beta := math.Asin(p.q(lat*deg2rad) / qP)
+	dLon := (lon - p.lon0) * deg2rad
+	b := rq * math.Sqrt(2/(1+math.Sin(beta0)*math.Sin(beta)+math.Cos(beta0)*math.Cos(beta)*math.Cos(dLon)))
+	x := p.falseEasting + b*d*math.Cos(beta)*math.Sin(dLon)
+	y := p.falseNorthing + (b/d)*(math.Cos(beta0)*math.Sin(beta)-math.Sin(beta0)*math.Cos(beta)*math.Cos(dLon))
+	return x, y
+}
+
+func (p lambertAzimuthalEqualArea) toGeographic(x, y float64) (float64, float64) {
+	_, beta0, rq, d := p.constants()
+	e := p.el.e()
+	e2, e4, e6 := e*e, math.Pow(e, 4), math.Pow(e, 6)
+	dx, dy := x-p.falseEasting, y-p.falseNorthing
+	rho := math.Hypot(dx/d, d*dy)
+	if rho == 0 {
+		return p.lon0, p.lat0
+	}
+	c := 2 * math.Asin(rho/(2*rq))
+	betaPrime := math.Asin(math.Cos(c)*math.Sin(beta0) + d*dy*math.Sin(c)*math.Cos(beta0)/rho)
+	lon := p.lon0*deg2rad + math.Atan2(dx*math.Sin(c), d*rho*math.Cos(beta0)*math.Cos(c)-d*d*dy*math.Sin(beta0)*math.Sin(c))
+	lat := betaPrime +
+		(e2/3+31*e4/180+517*e6/5040)*math.Sin(2*betaPrime) +
+		(23*e4/360+251*e6/3780)*math.Sin(4*betaPrime) +
+		(761*e6/45360)*math.Sin(6*betaPrime)
+	return lon * rad2deg, lat * rad2deg
+}
+
+// transverseMercator is the (ellipsoidal) transverse mercator projection with the origin on the equator,
+// using the Krüger series to the third order (accurate to about a millimetre within a UTM zone)
+type transverseMercator struct {
+	el                          ellipsoid
+	lon0                        float64 // degrees
+	k0                          float64
+	falseEasting, falseNorthing float64
+}
+
+// utm returns the transverse mercator projection of a UTM zone
+func utm(el ellipsoid, zone int, south bool) transverseMercator {
+	p := transverseMercator{el: el, lon0: float64(zone)*6 - 183, k0: 0.9996, falseEasting: 500000}
+	if south {
+		p.falseNorthing = 10000000
+	}
+	return p
+}
+
+func (p transverseMercator) series() (n, capitalA float64, alpha, beta, delta [3]float64) {
+	f := p.el.f()
+	n = f / (2 - f)
+	n2, n3 := n*n, n*n*n
+	capitalA = p.el.a / (1 + n) * (1 + n2/4 + n2*n2/64)
+	alpha = [3]float64{n/2 - 2*n2/3 + 5*n3/16, 13*n2/48 - 3*n3/5, 61 * n3 / 240}
+	beta = [3]float64{n/2 - 2*n2/3 + 37*n3/96, n2/48 + n3/15, 17 * n3 / 480}
+	delta = [3]float64{2*n - 2*n2/3 - 2*n3, 7*n2/3 - 8*n3/5, 56 * n3 / 15}
+	return
+}
+
+func (p transverseMercator) fromGeographic(lon, lat float64) (float64, float64) {
+	n, capitalA, alpha, _, _ := p.series()
+	phi := lat * deg2rad
+	dLon := (lon - p.lon0) * deg2rad
+	c := 2 * math.Sqrt(n) / (1 + n)
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - c*math.Atanh(c*math.Sin(phi)))
+	xiPrime := math.Atan2(t, math.Cos(dLon))
+	etaPrime := math.Atanh(math.Sin(dLon) / math.Sqrt(1+t*t))
+	xi, eta := xiPrime, etaPrime
+	for j := 1; j <= 3; j++ {
+		xi += alpha[j-1] * math.Sin(2*float64(j)*xiPrime) * math.Cosh(2*float64(j)*etaPrime)
+		eta += alpha[j-1] * math.Cos(2*float64(j)*xiPrime) * math.Sinh(2*float64(j)*etaPrime)
+	}
+	return p.falseEasting + p.k0*capitalA*eta, p.falseNorthing + p.k0*capitalA*xi
+}
+
+func (p transverseMercator) toGeographic(x, y float64) (float64, float64) {
+	_, capitalA, _, beta, delta := p.series()
+	xi := (y - p.falseNorthing) / (p.k0 * capitalA)
+	eta := (x - p.falseEasting) / (p.k0 * capitalA)
+	xiPrime, etaPrime := xi, eta
+	for j := 1; j <= 3; j++ {
+		xiPrime -= beta[j-1] * math.Sin(2*float64(j)*xi) * math.Cosh(2*float64(j)*eta)
+		etaPrime -= beta[j-1] * math.Cos(2*float64(j)*xi) * math.Sinh(2*float64(j)*eta)
+	}
+	chi := math.Asin(math.Sin(xiPrime) / math.Cosh(etaPrime))
+	phi := chi
+	for j := 1; j <= 3; j++ {
+		phi += delta[j-1] * math.Sin(2*float64(j)*chi)
+	}
+	lon := p.lon0 + math.Atan2(math.Sinh(etaPrime), math.Cos(xiPrime))*rad2deg
+	return lon, phi * rad2deg
+}
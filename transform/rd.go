@@ -0,0 +1,74 @@
+package transform
+
+import (
+	"math"
+)
+
+// RD New (EPSG:28992) is converted with the polynomial approximation of Schreutelaar
+// ("Benaderingsformules voor de transformatie tussen RD- en WGS84-kaartcoördinaten"),
+// which includes the datum shift and is accurate to about a metre. That suffices for snapping to vector tiles,
+// not for surveying (use RDNAPTRANS™ for that).
+
+const (
+	rdX0   = 155000.0
+	rdY0   = 463000.0
+	rdLat0 = 52.15517440
+	rdLon0 = 5.38720621
+)
+
+// polynomialTerm is a coefficient for the powers p and q of two variables
+type polynomialTerm struct {
+	p, q int
+	c    float64
+}
+
+var (
+	// latitude (in arcseconds) from dX and dY
+	rdK = []polynomialTerm{
+		{0, 1, 3235.65389}, {2, 0, -32.58297}, {0, 2, -0.24750}, {2, 1, -0.84978}, {0, 3, -0.06550},
+		{2, 2, -0.01709}, {1, 0, -0.00738}, {4, 0, 0.00530}, {2, 3, -0.00039}, {4, 1, 0.00033}, {1, 1, -0.00012},
+	}
+	// longitude (in arcseconds) from dX and dY
+	rdL = []polynomialTerm{
+		{1, 0, 5260.52916}, {1, 1, 105.94684}, {1, 2, 2.45656}, {3, 0, -0.81885}, {1, 3, 0.05594},
+		{3, 1, -0.05607}, {0, 1, 0.01199}, {3, 2, -0.00256}, {1, 4, 0.00128}, {0, 2, 0.00022},
+		{2, 0, -0.00022}, {5, 0, 0.00026},
+	}
+	// x from dLat and dLon
+	rdR = []polynomialTerm{
+		{0, 1, 190094.945}, {1, 1, -11832.228}, {2, 1, -114.221}, {0, 3, -32.391}, {1, 0, -0.705},
+		{3, 1, -2.340}, {1, 3, -0.608}, {0, 2, -0.008}, {2, 3, 0.148},
+	}
+	// y from dLat and dLon
+	rdS = []polynomialTerm{
+		{1, 0, 309056.544}, {0, 2, 3638.893}, {2, 0, 73.077}, {1, 2, -157.984}, {3, 0, 59.788},
+		{0, 1, 0.433}, {2, 2, -6.439}, {1, 1, -0.032}, {0, 4, 0.092}, {1, 4, -0.054},
+	}
+)
+
+func evalPolynomial(terms []polynomialTerm, a, b float64) float64 {
+	var sum float64
+	for _, term := range terms {
+		sum += term.c * math.Pow(a, float64(term.p)) * math.Pow(b, float64(term.q))
+	}
+	return sum
+}
+
+// rdNew is the RD New "projection", converting to and from WGS 84 (ETRS89) longitude, latitude
+type rdNew struct{}
+
+func (rdNew) toGeographic(x, y float64) (float64, float64) {
+	dX := (x - rdX0) * 1e-5
+	dY := (y - rdY0) * 1e-5
+	lat := rdLat0 + evalPolynomial(rdK, dX, dY)/3600
+	lon := rdLon0 + evalPolynomial(rdL, dX, dY)/3600
+	return lon, lat
+}
+
+func (rdNew) fromGeographic(lon, lat float64) (float64, float64) {
+	dLat := 0.36 * (lat - rdLat0)
+	dLon := 0.36 * (lon - rdLon0)
+	x := rdX0 + evalPolynomial(rdR, dLat, dLon)
+	y := rdY0 + evalPolynomial(rdS, dLat, dLon)
+	return x, y
+}
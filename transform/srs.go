@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SRS describes a CRS as in a GPKG's gpkg_spatial_ref_sys, for writing transformed geometries
+type SRS struct {
+	Name         string
+	Organization string
+	Code         int
+	// WKT (1) definition
+	Definition string
+}
+
+const (
+	wktGeogCSWGS84 = `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],` +
+		`PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]`
+	wktGeogCSETRS89 = `GEOGCS["ETRS89",DATUM["European_Terrestrial_Reference_System_1989",SPHEROID["GRS 1980",6378137,298.257222101,AUTHORITY["EPSG","7019"]],` +
+		`TOWGS84[0,0,0,0,0,0,0],AUTHORITY["EPSG","6258"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],` +
+		`UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4258"]]`
+	wktGeogCSNZGD2000 = `GEOGCS["NZGD2000",DATUM["New_Zealand_Geodetic_Datum_2000",SPHEROID["GRS 1980",6378137,298.257222101,AUTHORITY["EPSG","7019"]],` +
+		`TOWGS84[0,0,0,0,0,0,0],AUTHORITY["EPSG","6167"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],` +
+		`UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4167"]]`
+	wktUnitMetre = `UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Easting",EAST],AXIS["Northing",NORTH]`
+)
+
+var srss = map[int]SRS{
+	4326: {Name: "WGS 84", Definition: wktGeogCSWGS84},
+	4258: {Name: "ETRS89", Definition: wktGeogCSETRS89},
+	3857: {Name: "WGS 84 / Pseudo-Mercator", Definition: `PROJCS["WGS 84 / Pseudo-Mercator",` + wktGeogCSWGS84 + `,PROJECTION["Mercator_1SP"],` +
+		`PARAMETER["central_meridian",0],PARAMETER["scale_factor",1],PARAMETER["false_easting",0],PARAMETER["false_northing",0],` +
+		`UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["X",EAST],AXIS["Y",NORTH],` +
+		`EXTENSION["PROJ4","+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext +no_defs"],AUTHORITY["EPSG","3857"]]`},
+	3395: {Name: "WGS 84 / World Mercator", Definition: `PROJCS["WGS 84 / World Mercator",` + wktGeogCSWGS84 + `,PROJECTION["Mercator_1SP"],` +
+		`PARAMETER["central_meridian",0],PARAMETER["scale_factor",1],PARAMETER["false_easting",0],PARAMETER["false_northing",0],` +
+		wktUnitMetre + `,AUTHORITY["EPSG","3395"]]`},
+	3035: {Name: "ETRS89-extended / LAEA Europe", Definition: `PROJCS["ETRS89-extended / LAEA Europe",` + wktGeogCSETRS89 + `,PROJECTION["Lambert_Azimuthal_Equal_Area"],` +
+		`PARAMETER["latitude_of_center",52],PARAMETER["longitude_of_center",10],PARAMETER["false_easting",4321000],PARAMETER["false_northing",3210000],` +
+		`UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Northing",NORTH],AXIS["Easting",EAST],AUTHORITY["EPSG","3035"]]`},
+	28992: {Name: "Amersfoort / RD New", Definition: `PROJCS["Amersfoort / RD New",GEOGCS["Amersfoort",DATUM["Amersfoort",` +
+		`SPHEROID["Bessel 1841",6377397.155,299.1528128,AUTHORITY["EPSG","7004"]],` +
+		`TOWGS84[565.2369,50.0087,465.658,-0.406857,0.350733,-1.87035,4.0812],AUTHORITY["EPSG","6289"]],` +
+		`PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4289"]],` +
+		`PROJECTION["Oblique_Stereographic"],PARAMETER["latitude_of_origin",52.1561605555556],PARAMETER["central_meridian",5.38763888888889],` +
+		`PARAMETER["scale_factor",0.9999079],PARAMETER["false_easting",155000],PARAMETER["false_northing",463000],` +
+		wktUnitMetre + `,AUTHORITY["EPSG","28992"]]`},
+	2193: {Name: "NZGD2000 / New Zealand Transverse Mercator 2000", Definition: `PROJCS["NZGD2000 / New Zealand Transverse Mercator 2000",` +
+		wktGeogCSNZGD2000 + `,PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",173],` +
+		`PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",1600000],PARAMETER["false_northing",10000000],` +
+		`UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Northing",NORTH],AXIS["Easting",EAST],AUTHORITY["EPSG","2193"]]`},
+}
+
+// SameSRS returns whether two CRSs (given as authority:code) are the same in a GPKG.
+// OGC:CRS84 is stored as EPSG:4326 (x is longitude).
+func SameSRS(authorityCode string, otherAuthorityCode string) bool {
+	return gpkgAuthorityCode(authorityCode) == gpkgAuthorityCode(otherAuthorityCode)
+}
+
+func gpkgAuthorityCode(authorityCode string) string {
+	authorityCode = strings.ToUpper(authorityCode)
+	if authorityCode == "OGC:CRS84" {
+		return "EPSG:4326"
+	}
+	return authorityCode
+}
+
+// SRSFor returns the description of a supported CRS (given as authority:code, e.g. EPSG:28992)
+func SRSFor(authorityCode string) (SRS, error) {
+	if !Supported(authorityCode) {
+		return SRS{}, fmt.Errorf(`unsupported crs: %s`, authorityCode)
+	}
+	_, code, _ := strings.Cut(strings.ToUpper(authorityCode), ":")
+	if code == "CRS84" {
+		// stored the same way as EPSG:4326 in a GPKG (x is longitude)
+		code = "4326"
+	}
+	epsgCode, _ := strconv.Atoi(code)
+	srs, ok := srss[epsgCode]
+	if !ok {
+		srs = utmSRS(epsgCode)
+	}
+	srs.Organization = "EPSG"
+	srs.Code = epsgCode
+	return srs, nil
+}
+
+// utmSRS describes one of the WGS 84 or ETRS89 UTM zones
+func utmSRS(epsgCode int) SRS {
+	geogCS, datumName := wktGeogCSWGS84, "WGS 84"
+	zone, falseNorthing, hemisphere := epsgCode-32600, 0, "N"
+	switch {
+	case epsgCode > 32700:
+		zone, falseNorthing, hemisphere = epsgCode-32700, 10000000, "S"
+	case epsgCode < 32600:
+		geogCS, datumName = wktGeogCSETRS89, "ETRS89"
+		zone = epsgCode - 25800
+	}
+	name := fmt.Sprintf("%s / UTM zone %d%s", datumName, zone, hemisphere)
+	return SRS{
+		Name: name,
+		Definition: fmt.Sprintf(`PROJCS["%s",%s,PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",0],PARAMETER["central_meridian",%d],`+
+			`PARAMETER["scale_factor",0.9996],PARAMETER["false_easting",500000],PARAMETER["false_northing",%d],%s,AUTHORITY["EPSG","%d"]]`,
+			name, geogCS, zone*6-183, falseNorthing, wktUnitMetre, epsgCode),
+	}
+}
@@ -0,0 +1,133 @@
+// Package transform reprojects geometries between the CRSs of the embedded tile matrix sets, in pure Go.
+// All (WGS 84 based) datums are treated as the same (ETRS89 differs less than a metre from WGS 84),
+// which suffices for snapping to vector tiles.
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-spatial/geom"
+)
+
+// Transformer reprojects from one CRS to another, by way of geographic coordinates
+type Transformer struct {
+	from projection
+	to   projection
+}
+
+// New returns a transformer between two CRSs, given as authority:code (e.g. EPSG:28992)
+func New(from, to string) (*Transformer, error) {
+	fromProjection, err := projectionFor(from)
+	if err != nil {
+		return nil, err
+	}
+	toProjection, err := projectionFor(to)
+	if err != nil {
+		return nil, err
+	}
+	return &Transformer{from: fromProjection, to: toProjection}, nil
+}
+
+// Supported checks whether a CRS (given as authority:code) can be transformed from and to
+func Supported(authorityCode string) bool {
+	_, err := projectionFor(authorityCode)
+	return err == nil
+}
+
+//nolint:cyclop
+func projectionFor(authorityCode string) (projection, error) {
+	authority, code, _ := strings.Cut(strings.ToUpper(authorityCode), ":")
+	if authority == "OGC" && code == "CRS84" {
+		return geographic{}, nil
+	}
+	epsgCode, err := strconv.Atoi(code)
+	if authority != "EPSG" || err != nil {
+		return nil, fmt.Errorf(`unsupported crs for transforming: %s`, authorityCode)
+	}
+	switch {
+	case epsgCode == 4326 || epsgCode == 4258:
+		return geographic{}, nil
+	case epsgCode == 3857:
+		return webMercator{}, nil
+	case epsgCode == 3395:
+		return mercator{el: wgs84}, nil
+	case epsgCode == 3035:
+		return lambertAzimuthalEqualArea{el: grs80, lon0: 10, lat0: 52, falseEasting: 4321000, falseNorthing: 3210000}, nil
+	case epsgCode == 28992:
+		return rdNew{}, nil
+	case epsgCode == 2193: // NZTM2000
+		return transverseMercator{el: grs80, lon0: 173, k0: 0.9996, falseEasting: 1600000, falseNorthing: 10000000}, nil
+	case 32601 <= epsgCode && epsgCode <= 32660: // WGS 84 / UTM north
+		return utm(wgs84, epsgCode-32600, false), nil
+	case 32701 <= epsgCode && epsgCode <= 32760: // WGS 84 / UTM south
+		return utm(wgs84, epsgCode-32700, true), nil
+	case 25828 <= epsgCode && epsgCode <= 25838: // ETRS89 / UTM
+		return utm(grs80, epsgCode-25800, false), nil
+	default:
+		return nil, fmt.Errorf(`unsupported crs for transforming: %s`, authorityCode)
+	}
+}
+
+// Point transforms a point (in x, y order)
+func (t *Transformer) Point(pt geom.Point) geom.Point {
+	lon, lat := t.from.toGeographic(pt[0], pt[1])
+	x, y := t.to.fromGeographic(lon, lat)
+	return geom.Point{x, y}
+}
+
+func (t *Transformer) points(points [][2]float64) [][2]float64 {
+	transformed := make([][2]float64, len(points))
+	for i, pt := range points {
+		transformed[i] = t.Point(pt)
+	}
+	return transformed
+}
+
+func (t *Transformer) Polygon(polygon geom.Polygon) geom.Polygon {
+	transformed := make(geom.Polygon, len(polygon))
+	for i, ring := range polygon {
+		transformed[i] = t.points(ring)
+	}
+	return transformed
+}
+
+// Geometry transforms a (2D) geometry
+func (t *Transformer) Geometry(g geom.Geometry) (geom.Geometry, error) {
+	switch g := g.(type) {
+	case nil:
+		return nil, nil
+	case geom.Point:
+		return t.Point(g), nil
+	case geom.MultiPoint:
+		return geom.MultiPoint(t.points(g)), nil
+	case geom.LineString:
+		return geom.LineString(t.points(g)), nil
+	case geom.MultiLineString:
+		transformed := make(geom.MultiLineString, len(g))
+		for i, lineString := range g {
+			transformed[i] = t.points(lineString)
+		}
+		return transformed, nil
+	case geom.Polygon:
+		return t.Polygon(g), nil
+	case geom.MultiPolygon:
+		transformed := make(geom.MultiPolygon, len(g))
+		for i, polygon := range g {
+			transformed[i] = t.Polygon(polygon)
+		}
+		return transformed, nil
+	case geom.Collection:
+		transformed := make(geom.Collection, len(g))
+		for i, geometry := range g {
+			var err error
+			if transformed[i], err = t.Geometry(geometry); err != nil {
+				return nil, err
+			}
+		}
+		return transformed, nil
+	default:
+		return nil, fmt.Errorf(`unsupported geometry type for transforming: %T`, g)
+	}
+}
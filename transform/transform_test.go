@@ -0,0 +1,114 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformer_Point(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		pt       geom.Point
+		want     geom.Point
+		delta    float64
+	}{
+		{name: "RD reference point", from: "EPSG:28992", to: "EPSG:4326", pt: geom.Point{155000, 463000}, want: geom.Point{5.38720621, 52.15517440}, delta: 1e-9},
+		{name: "RD Westertoren", from: "EPSG:4326", to: "EPSG:28992", pt: geom.Point{4.88352559, 52.37453253}, want: geom.Point{120700.723, 487525.501}, delta: 1},
+		{name: "LAEA EPSG example", from: "EPSG:4258", to: "EPSG:3035", pt: geom.Point{5, 50}, want: geom.Point{3962799.45, 2999718.85}, delta: 0.01},
+		{name: "LAEA origin", from: "EPSG:3035", to: "OGC:CRS84", pt: geom.Point{4321000, 3210000}, want: geom.Point{10, 52}, delta: 1e-9},
+		{name: "web mercator", from: "EPSG:4326", to: "EPSG:3857", pt: geom.Point{180, 85.0511287798066}, want: geom.Point{20037508.342789244, 20037508.342789244}, delta: 1e-3},
+		{name: "world mercator equator", from: "EPSG:4326", to: "EPSG:3395", pt: geom.Point{-90, 0}, want: geom.Point{-10018754.171394622, 0}, delta: 1e-6},
+		{name: "UTM central meridian on equator", from: "EPSG:4326", to: "EPSG:32631", pt: geom.Point{3, 0}, want: geom.Point{500000, 0}, delta: 1e-6},
+		{name: "UTM south", from: "EPSG:4326", to: "EPSG:32731", pt: geom.Point{3, 0}, want: geom.Point{500000, 10000000}, delta: 1e-6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := New(tt.from, tt.to)
+			require.NoError(t, err)
+			got := transformer.Point(tt.pt)
+			assert.InDeltaSlice(t, tt.want[:], got[:], tt.delta)
+		})
+	}
+}
+
+func TestTransformer_roundTrip(t *testing.T) {
+	tests := []struct {
+		crs   string
+		pts   []geom.Point
+		delta float64
+	}{
+		{crs: "EPSG:28992", pts: []geom.Point{{121000, 487000}, {250000, 600000}, {30000, 370000}}, delta: 0.5},
+		{crs: "EPSG:3857", pts: []geom.Point{{545000, 6868000}, {-8000000, -4000000}}, delta: 1e-6},
+		{crs: "EPSG:3395", pts: []geom.Point{{545000, 6830000}, {-8000000, -4000000}}, delta: 1e-6},
+		{crs: "EPSG:3035", pts: []geom.Point{{3962799.45, 2999718.85}, {2000000, 1000000}}, delta: 1e-2},
+		{crs: "EPSG:32631", pts: []geom.Point{{628000, 5804000}, {300000, 1000000}}, delta: 1e-3},
+		{crs: "EPSG:25832", pts: []geom.Point{{500000, 5500000}}, delta: 1e-3},
+		{crs: "EPSG:2193", pts: []geom.Point{{1750000, 5430000}}, delta: 1e-3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.crs, func(t *testing.T) {
+			forward, err := New(tt.crs, "EPSG:4326")
+			require.NoError(t, err)
+			backward, err := New("EPSG:4326", tt.crs)
+			require.NoError(t, err)
+			for _, pt := range tt.pts {
+				got := backward.Point(forward.Point(pt))
+				assert.InDeltaSlice(t, pt[:], got[:], tt.delta, pt)
+			}
+		})
+	}
+}
+
+func TestTransformer_Geometry(t *testing.T) {
+	transformer, err := New("EPSG:3857", "EPSG:4326")
+	require.NoError(t, err)
+	got, err := transformer.Geometry(geom.MultiPolygon{{{{0, 0}, {20037508.342789244, 0}, {0, 0}}}})
+	require.NoError(t, err)
+	multiPolygon, ok := got.(geom.MultiPolygon)
+	require.True(t, ok)
+	assert.InDeltaSlice(t, []float64{180, 0}, multiPolygon[0][0][1][:], 1e-9)
+}
+
+func TestNew_unsupported(t *testing.T) {
+	_, err := New("EPSG:3978", "EPSG:4326")
+	assert.ErrorContains(t, err, "unsupported crs for transforming: EPSG:3978")
+	assert.False(t, Supported("EPSG:5041"))
+	assert.True(t, Supported("epsg:28992"))
+}
+
+func TestSameSRS(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "EPSG:28992", b: "EPSG:28992", want: true},
+		{a: "epsg:28992", b: "EPSG:28992", want: true},
+		{a: "OGC:CRS84", b: "EPSG:4326", want: true},
+		{a: "EPSG:4326", b: "ogc:crs84", want: true},
+		{a: "EPSG:4326", b: "EPSG:4258", want: false},
+		{a: "EPSG:28992", b: "EPSG:3857", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equalf(t, tt.want, SameSRS(tt.a, tt.b), "SameSRS(%s, %s)", tt.a, tt.b)
+	}
+}
+
+func TestSRSFor(t *testing.T) {
+	srs, err := SRSFor("EPSG:32631")
+	require.NoError(t, err)
+	assert.Equal(t, "WGS 84 / UTM zone 31N", srs.Name)
+	assert.Equal(t, 32631, srs.Code)
+	assert.Contains(t, srs.Definition, `PARAMETER["central_meridian",3]`)
+
+	srs, err = SRSFor("OGC:CRS84")
+	require.NoError(t, err)
+	assert.Equal(t, 4326, srs.Code)
+
+	srs, err = SRSFor("EPSG:25831")
+	require.NoError(t, err)
+	assert.Equal(t, "ETRS89 / UTM zone 31N", srs.Name)
+}
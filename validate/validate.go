@@ -0,0 +1,290 @@
+// Package validate checks geometries against texel's definition of "valid" (see README):
+// overlap is allowed, intersections are not and rings with less than 3 vertices (points and lines) are allowed.
+package validate
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/go-spatial/geom"
+	"github.com/go-spatial/geom/encoding/wkt"
+	"github.com/pdok/texel/geomhelp"
+	"github.com/pdok/texel/tms20"
+	"golang.org/x/exp/maps"
+)
+
+type Kind string
+
+const (
+	// SelfIntersection is a ring crossing itself
+	SelfIntersection Kind = "self-intersection"
+	// RingCrossing is a ring crossing another ring (of the same polygon or another polygon of the same feature)
+	RingCrossing Kind = "ring crossing"
+	// InnerOutsideOuter is an inner ring (partly) outside its outer ring
+	InnerOutsideOuter Kind = "inner ring outside outer ring"
+)
+
+// Finding is a single violation of texel's definition of valid
+type Finding struct {
+	Kind Kind
+	// FID of the feature, if known
+	FID int64
+	// TileMatrixID (cq level) the geometry was snapped for, if known
+	TileMatrixID tms20.TMID
+	Location     geom.Point
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("fid %d, tile matrix %d: %s at %s", f.FID, f.TileMatrixID, f.Kind, wkt.MustEncode(f.Location))
+}
+
+// Geometry validates a (multi)polygon. Other geometry types are valid by definition.
+func Geometry(g geom.Geometry) []Finding {
+	switch g := g.(type) {
+	case geom.Polygon:
+		return Polygons([]geom.Polygon{g})
+	case geom.MultiPolygon:
+		polygons := make([]geom.Polygon, len(g))
+		for i := range g {
+			polygons[i] = g[i]
+		}
+		return Polygons(polygons)
+	default:
+		return nil
+	}
+}
+
+// TileMatrixPolygons validates the polygons per tile matrix, like the result of snap.SnapPolygon.
+// The findings are sorted by tile matrix ID.
+func TileMatrixPolygons(polygonsPerTileMatrix map[tms20.TMID][]geom.Polygon) []Finding {
+	tmIDs := maps.Keys(polygonsPerTileMatrix)
+	slices.Sort(tmIDs)
+	var findings []Finding
+	for _, tmID := range tmIDs {
+		for _, finding := range Polygons(polygonsPerTileMatrix[tmID]) {
+			finding.TileMatrixID = tmID
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// Polygons validates polygons that together form one feature.
+// So the rings of the different polygons are not allowed to cross each other either.
+func Polygons(polygons []geom.Polygon) []Finding {
+	findings := findCrossings(polygons)
+	for _, polygon := range polygons {
+		findings = append(findings, findInnersOutsideOuter(polygon)...)
+	}
+	return findings
+}
+
+type segment struct {
+	line     [2][2]float64
+	polygonI int
+	ringI    int
+	extent   geom.Extent
+}
+
+// pass is a ring passing through a point, through one of its vertices or the interior of one of its edges
+type pass struct {
+	polygonI int
+	ringI    int
+	// rays are the directions from the point to the neighbours on the ring
+	rays   [2][2]float64
+	onEdge bool
+}
+
+// findCrossings finds the crossing segments with a sweep line over x,
+// and the rings crossing each other (or themselves) through a shared vertex (or a vertex on an edge)
+func findCrossings(polygons []geom.Polygon) []Finding {
+	var segments []segment
+	passes := make(map[[2]float64][]pass)
+	for polygonI, polygon := range polygons {
+		for ringI, ring := range polygon {
+			segments = append(segments, ringSegments(ring, polygonI, ringI)...)
+			addVertexPasses(passes, ring, polygonI, ringI)
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].extent.MinX() < segments[j].extent.MinX()
+	})
+
+	var findings []Finding
+	for i := range segments {
+		segI := segments[i]
+		for j := i + 1; j < len(segments) && segments[j].extent.MinX() <= segI.extent.MaxX(); j++ {
+			segJ := segments[j]
+			if segJ.extent.MinY() > segI.extent.MaxY() || segJ.extent.MaxY() < segI.extent.MinY() {
+				continue
+			}
+			intersection, crosses := geomhelp.SegmentsCross(segI.line[0], segI.line[1], segJ.line[0], segJ.line[1])
+			if !crosses {
+				addEdgePasses(passes, segI, segJ)
+				addEdgePasses(passes, segJ, segI)
+				continue
+			}
+			kind := RingCrossing
+			if segI.polygonI == segJ.polygonI && segI.ringI == segJ.ringI {
+				kind = SelfIntersection
+			}
+			findings = append(findings, Finding{Kind: kind, Location: intersection})
+		}
+	}
+	return append(findings, findVertexCrossings(passes)...)
+}
+
+// addVertexPasses adds the passes of a ring through its vertices. Points and lines don't pass through their vertices.
+func addVertexPasses(passes map[[2]float64][]pass, ring [][2]float64, polygonI, ringI int) {
+	if len(ring) < 3 {
+		return
+	}
+	for i, vertex := range ring {
+		prev, next := ring[(i+len(ring)-1)%len(ring)], ring[(i+1)%len(ring)]
+		if prev == vertex || next == vertex {
+			continue // no direction
+		}
+		passes[vertex] = append(passes[vertex], pass{
+			polygonI: polygonI,
+			ringI:    ringI,
+			rays:     [2][2]float64{sub(prev, vertex), sub(next, vertex)},
+		})
+	}
+}
+
+// addEdgePasses adds the pass of the segment's ring through the end points of the other segment
+// that lie in the interior of the segment
+func addEdgePasses(passes map[[2]float64][]pass, seg segment, other segment) {
+	for _, point := range other.line {
+		if point == seg.line[0] || point == seg.line[1] || !seg.extent.ContainsPoint(point) ||
+			geomhelp.Orientation(seg.line[0], seg.line[1], point) != 0 {
+			continue
+		}
+		edgePass := pass{
+			polygonI: seg.polygonI,
+			ringI:    seg.ringI,
+			rays:     [2][2]float64{sub(seg.line[0], point), sub(seg.line[1], point)},
+			onEdge:   true,
+		}
+		if !slices.Contains(passes[point], edgePass) { // the point is the end point of two segments of the other ring
+			passes[point] = append(passes[point], edgePass)
+		}
+	}
+}
+
+// findVertexCrossings finds the rings that pass through the same point and cross each other there,
+// meaning the rays of one lie on both sides of the other. Touching, or sharing an edge, is not crossing.
+func findVertexCrossings(passes map[[2]float64][]pass) []Finding {
+	points := maps.Keys(passes)
+	slices.SortFunc(points, func(a, b [2]float64) int {
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		return cmp.Compare(a[1], b[1])
+	})
+	var findings []Finding
+	for _, point := range points {
+		pointPasses := passes[point]
+		for i := range pointPasses {
+			for j := i + 1; j < len(pointPasses); j++ {
+				passI, passJ := pointPasses[i], pointPasses[j]
+				if passI.onEdge && passJ.onEdge { // crossing in the interior of both, found by the sweep
+					continue
+				}
+				if !separates(passI, passJ) {
+					continue
+				}
+				kind := RingCrossing
+				if passI.polygonI == passJ.polygonI && passI.ringI == passJ.ringI {
+					kind = SelfIntersection
+				}
+				findings = append(findings, Finding{Kind: kind, Location: point})
+			}
+		}
+	}
+	return findings
+}
+
+// separates returns whether the rays of pass b lie on either side of the rays of pass a.
+// Not if any of the rays coincide, then the rings (partly) share an edge.
+func separates(a, b pass) bool {
+	a1, a2 := a.rays[0], a.rays[1]
+	if sameDirection(a1, a2) {
+		return false // a spike
+	}
+	for _, ray := range b.rays {
+		if sameDirection(ray, a1) || sameDirection(ray, a2) {
+			return false
+		}
+	}
+	return inSector(a1, a2, b.rays[0]) != inSector(a1, a2, b.rays[1])
+}
+
+// inSector returns whether v lies in the sector counterclockwise from u1 to u2, v not lying along u1 or u2
+func inSector(u1, u2, v [2]float64) bool {
+	switch c := cross(u1, u2); {
+	case c > 0: // less than half a turn
+		return cross(u1, v) > 0 && cross(v, u2) > 0
+	case c < 0: // more than half a turn
+		return cross(u1, v) >= 0 || cross(v, u2) >= 0
+	default: // opposite, half a turn
+		return cross(u1, v) > 0
+	}
+}
+
+func sameDirection(u, v [2]float64) bool {
+	return cross(u, v) == 0 && u[0]*v[0]+u[1]*v[1] > 0
+}
+
+func cross(u, v [2]float64) float64 {
+	return u[0]*v[1] - u[1]*v[0]
+}
+
+func sub(a, b [2]float64) [2]float64 {
+	return [2]float64{a[0] - b[0], a[1] - b[1]}
+}
+
+func ringSegments(ring [][2]float64, polygonI, ringI int) []segment {
+	ringLen := len(ring)
+	switch ringLen {
+	case 0, 1:
+		return nil
+	case 2:
+		// a line, the closing segment would be the same line reversed
+		ringLen = 1
+	}
+	segments := make([]segment, ringLen)
+	for i := 0; i < ringLen; i++ {
+		line := [2][2]float64{ring[i], ring[(i+1)%len(ring)]}
+		segments[i] = segment{
+			line:     line,
+			polygonI: polygonI,
+			ringI:    ringI,
+			extent:   *geom.NewExtent(line[0], line[1]),
+		}
+	}
+	return segments
+}
+
+// findInnersOutsideOuter finds inner rings with vertices outside the outer ring
+func findInnersOutsideOuter(polygon geom.Polygon) []Finding {
+	if len(polygon) < 2 {
+		return nil
+	}
+	outer := polygon[0]
+	var findings []Finding
+	for _, inner := range polygon[1:] {
+		for _, vertex := range inner {
+			if len(outer) >= 3 {
+				if contains, _ := geomhelp.RingContains(outer, vertex); contains {
+					continue
+				}
+			}
+			findings = append(findings, Finding{Kind: InnerOutsideOuter, Location: vertex})
+			break
+		}
+	}
+	return findings
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/go-spatial/geom"
+	"github.com/pdok/texel/tms20"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolygons(t *testing.T) {
+	tests := []struct {
+		name     string
+		polygons []geom.Polygon
+		want     []Finding
+	}{
+		{
+			name:     "square",
+			polygons: []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}},
+			want:     nil,
+		},
+		{
+			name:     "bow-tie",
+			polygons: []geom.Polygon{{{{0, 0}, {4, 4}, {4, 0}, {0, 4}}}},
+			want:     []Finding{{Kind: SelfIntersection, Location: geom.Point{2, 2}}},
+		},
+		{
+			name:     "bow-tie through a vertex",
+			polygons: []geom.Polygon{{{{0, 0}, {2, 2}, {4, 4}, {4, 0}, {2, 2}, {0, 4}}}},
+			want:     []Finding{{Kind: SelfIntersection, Location: geom.Point{2, 2}}},
+		},
+		{
+			name:     "touching itself at a vertex",
+			polygons: []geom.Polygon{{{{0, 0}, {2, 2}, {4, 0}, {4, 4}, {2, 2}, {0, 4}}}},
+			want:     nil,
+		},
+		{
+			name: "crossing through shared vertices",
+			polygons: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}},
+				{{{4, 4}, {3, 1}, {0, 0}, {-2, 1}, {1, 6}}},
+			},
+			want: []Finding{
+				{Kind: RingCrossing, Location: geom.Point{0, 0}},
+				{Kind: RingCrossing, Location: geom.Point{4, 4}},
+			},
+		},
+		{
+			name: "crossing through a vertex on an edge",
+			polygons: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}},
+				{{{2, 4}, {3, 2}, {5, 2}, {5, 6}}},
+			},
+			want: []Finding{
+				{Kind: RingCrossing, Location: geom.Point{4, 2}},
+				{Kind: RingCrossing, Location: geom.Point{2, 4}},
+			},
+		},
+		{
+			name: "touching at a shared vertex",
+			polygons: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}},
+				{{{4, 4}, {8, 4}, {8, 8}, {4, 8}}},
+			},
+			want: nil,
+		},
+		{
+			name:     "points and lines are allowed",
+			polygons: []geom.Polygon{{{{0, 0}, {4, 4}}}, {{{2, 0}}}},
+			want:     nil,
+		},
+		{
+			name:     "line crossing an outer ring",
+			polygons: []geom.Polygon{{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}, {{{2, -1}, {2, 1}}}},
+			want:     []Finding{{Kind: RingCrossing, Location: geom.Point{2, 0}}},
+		},
+		{
+			name: "overlap is allowed",
+			polygons: []geom.Polygon{
+				{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}},
+				{{{4, 0}, {8, 0}, {8, 4}, {4, 4}}},
+				{{{1, 1}, {3, 1}, {3, 3}, {1, 3}}},
+			},
+			want: nil,
+		},
+		{
+			name: "touching inner ring",
+			polygons: []geom.Polygon{{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+				{{0, 0}, {2, 3}, {3, 2}},
+			}},
+			want: nil,
+		},
+		{
+			name: "inner crossing outer",
+			polygons: []geom.Polygon{{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+				{{1, 1}, {1, 3}, {5, 3}, {5, 1}},
+			}},
+			want: []Finding{
+				{Kind: RingCrossing, Location: geom.Point{4, 3}},
+				{Kind: RingCrossing, Location: geom.Point{4, 1}},
+				{Kind: InnerOutsideOuter, Location: geom.Point{5, 3}},
+			},
+		},
+		{
+			name: "inner outside outer",
+			polygons: []geom.Polygon{{
+				{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+				{{5, 1}, {5, 3}, {7, 3}, {7, 1}},
+			}},
+			want: []Finding{{Kind: InnerOutsideOuter, Location: geom.Point{5, 1}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equalf(t, tt.want, Polygons(tt.polygons), "Polygons(%v)", tt.polygons)
+		})
+	}
+}
+
+func TestTileMatrixPolygons(t *testing.T) {
+	polygonsPerTileMatrix := map[tms20.TMID][]geom.Polygon{
+		5: {{{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}},
+		6: {{{{0, 0}, {4, 4}, {4, 0}, {0, 4}}}},
+		7: {{{{0, 0}, {4, 4}, {4, 0}, {0, 4}}}},
+	}
+	want := []Finding{
+		{Kind: SelfIntersection, TileMatrixID: 6, Location: geom.Point{2, 2}},
+		{Kind: SelfIntersection, TileMatrixID: 7, Location: geom.Point{2, 2}},
+	}
+	assert.Equal(t, want, TileMatrixPolygons(polygonsPerTileMatrix))
+}